@@ -0,0 +1 @@
+package {{ .Orbit.name }}
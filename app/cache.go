@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// olderThan is the duration after which an unused cache entry is pruned.
+	olderThan time.Duration
+
+	// cacheCmd is the instance of cache command.
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manages the cache of the files generated by the tasks",
+		Long:  "Manages the cache of the files generated by the tasks (by default ~/.cache/orbit, or the ORBIT_CACHE_DIR environment variable).",
+	}
+
+	// cacheStatsCmd is the instance of cache stats command.
+	cacheStatsCmd = &cobra.Command{
+		Use:           "stats",
+		Short:         "Prints statistics about the cache",
+		Long:          "Prints statistics about the cache.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          cacheStats,
+	}
+
+	// cacheCleanCmd is the instance of cache clean command.
+	cacheCleanCmd = &cobra.Command{
+		Use:           "clean",
+		Short:         "Removes everything from the cache",
+		Long:          "Removes everything from the cache.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          cacheClean,
+	}
+
+	// cachePruneCmd is the instance of cache prune command.
+	cachePruneCmd = &cobra.Command{
+		Use:           "prune",
+		Short:         "Removes the cache entries which have not been used for a while",
+		Long:          "Removes the cache entries which have not been used for the duration given by the --older-than flag.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          cachePrune,
+	}
+)
+
+// init initializes the cache commands with some flags and adds them to the RootCmd.
+func init() {
+	cachePruneCmd.Flags().DurationVar(&olderThan, "older-than", 0, "specify how long an entry may stay unused before being removed (e.g. 168h)")
+	cacheCmd.AddCommand(cacheStatsCmd, cacheCleanCmd, cachePruneCmd)
+	RootCmd.AddCommand(cacheCmd)
+}
+
+// cacheStats prints statistics about the cache.
+func cacheStats(cmd *cobra.Command, args []string) error {
+	c := runner.NewOrbitCache(runner.DefaultCacheDir())
+
+	stats, err := c.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Directory: %s\nEntries:   %d\nFiles:     %d\nSize:      %d bytes\n", c.Dir(), stats.Entries, stats.Files, stats.Size)
+
+	return nil
+}
+
+// cacheClean removes everything from the cache.
+func cacheClean(cmd *cobra.Command, args []string) error {
+	return runner.NewOrbitCache(runner.DefaultCacheDir()).Clean()
+}
+
+// cachePrune removes the cache entries which have not been used for a while.
+func cachePrune(cmd *cobra.Command, args []string) error {
+	if olderThan <= 0 {
+		return OrbitError.NewOrbitError("the --older-than flag must be a positive duration (e.g. 168h)")
+	}
+
+	removed, err := runner.NewOrbitCache(runner.DefaultCacheDir()).Prune(olderThan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d entry(ies) removed\n", removed)
+
+	return nil
+}
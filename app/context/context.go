@@ -6,6 +6,11 @@ The main goal of the application context is to gather all the data which will be
 package context
 
 import (
+	gocontext "context"
+	"io"
+	"io/ioutil"
+	"os"
+
 	OrbitError "github.com/gulien/orbit/app/error"
 	"github.com/gulien/orbit/app/helpers"
 	"github.com/gulien/orbit/app/logger"
@@ -16,6 +21,10 @@ type OrbitContext struct {
 	// TemplateFilePath is the path of a data-driven template.
 	TemplateFilePath string
 
+	// TemplateContent is the content of the data-driven template
+	// if read from Stdin, nil otherwise.
+	TemplateContent []byte
+
 	// Payload map contains data from various entries.
 	Payload map[string]interface{}
 
@@ -24,57 +33,181 @@ type OrbitContext struct {
 
 	// Optional pair of template delimiters (used to override go defaults "{{" and "}}")
 	TemplateDelimiters []string
+
+	// Overrides map contains the values given by the user
+	// which take precedence over the payload.
+	Overrides map[string]interface{}
 }
 
+// StdinFilePath is the path of a data-driven template which is read from Stdin.
+const StdinFilePath = "-"
+
+// stdin is the reader of a data-driven template read from Stdin.
+var stdin io.Reader = os.Stdin
+
 // NewOrbitContext creates an instance of OrbitContext.
-func NewOrbitContext(templateFilePath string, payload string, templates string, templateDelimiters []string) (*OrbitContext, error) {
+// The given context allows to cancel the decoding of the payload.
+func NewOrbitContext(ctx gocontext.Context, templateFilePath string, payload string, templates string, templateDelimiters []string) (*OrbitContext, error) {
+	// let's instantiates our OrbitContext!
+	orbitContext := &OrbitContext{
+		TemplateFilePath: templateFilePath,
+	}
+
 	// as the data-driven template is mandatory, we must check its validity.
+	if templateFilePath == StdinFilePath {
+		content, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, OrbitError.NewOrbitErrorf("unable to read the data-driven template from Stdin. Details:\n%s", err)
+		}
+
+		orbitContext.TemplateContent = content
+	} else if err := checkTemplateFilePath(templateFilePath); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("context has been instantiated with the data-driven template %s", orbitContext.TemplateFilePath)
+
+	// last but not least, retrieves the data provided by the entries given by the user.
+	p, payloadData, err := retrievePayload(ctx, payload, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	orbitContext.Payload = payloadData
+	logger.Debugf("context has been populated with payload %s", orbitContext.Payload)
+
+	orbitContext.Templates = p.TemplatesEntries
+	logger.Debugf("context has been populated with templates %s", orbitContext.Templates)
+
+	if orbitContext.TemplateDelimiters, err = checkTemplateDelimiters(templateDelimiters); err != nil {
+		return nil, err
+	}
+
+	return orbitContext, nil
+}
+
+/*
+NewOrbitContextFromParent creates an instance of OrbitContext for another data-driven template
+which shares the payload, the additional templates and the values given by the user of the given
+parent, so that the payload is only decoded once for many templates.
+
+The given entries are merged over the payload of the parent, the given additional templates are
+added to the ones of the parent, and the given delimiters replace the ones of the parent if not nil.
+*/
+func NewOrbitContextFromParent(ctx gocontext.Context, parent *OrbitContext, templateFilePath string, payload string, templates string, templateDelimiters []string) (*OrbitContext, error) {
+	if err := checkTemplateFilePath(templateFilePath); err != nil {
+		return nil, err
+	}
+
+	orbitContext := &OrbitContext{
+		TemplateFilePath:   templateFilePath,
+		Payload:            parent.Payload,
+		Templates:          parent.Templates,
+		TemplateDelimiters: parent.TemplateDelimiters,
+		Overrides:          parent.Overrides,
+	}
+
+	if payload != "" || templates != "" {
+		p := &orbitPayload{}
+		if err := p.populateFromString(payload, templates); err != nil {
+			return nil, err
+		}
+
+		payloadData, err := p.retrieve(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		orbitContext.Payload = MergeData(parent.Payload, payloadData)
+		orbitContext.Templates = append(append([]string{}, parent.Templates...), p.TemplatesEntries...)
+	}
+
+	if templateDelimiters != nil {
+		var err error
+		if orbitContext.TemplateDelimiters, err = checkTemplateDelimiters(templateDelimiters); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debugf("context has been instantiated with the data-driven template %s from the context of %s", orbitContext.TemplateFilePath, parent.TemplateFilePath)
+
+	return orbitContext, nil
+}
+
+// checkTemplateFilePath returns an error if the given data-driven template is missing.
+func checkTemplateFilePath(templateFilePath string) error {
 	if templateFilePath == "" {
-		return nil, OrbitError.NewOrbitErrorf("no data-driven template given")
+		return OrbitError.NewOrbitErrorf("no data-driven template given")
 	}
 
 	if !helpers.FileExists(templateFilePath) {
-		return nil, OrbitError.NewOrbitErrorf("the data-driven template %s does not exist", templateFilePath)
+		return OrbitError.NewOrbitErrorf("the data-driven template %s does not exist", templateFilePath)
 	}
 
-	// let's instantiates our OrbitContext!
-	ctx := &OrbitContext{
-		TemplateFilePath: templateFilePath,
+	return nil
+}
+
+// checkTemplateDelimiters returns the given pair of template delimiters, or an empty pair if nil.
+func checkTemplateDelimiters(templateDelimiters []string) ([]string, error) {
+	if templateDelimiters == nil {
+		return make([]string, 2), nil
+	}
+
+	if len(templateDelimiters) != 2 {
+		return nil, OrbitError.NewOrbitErrorf("%d delimiter(s) specified: %+v. Exactly two (left,right) must be specified", len(templateDelimiters), templateDelimiters)
 	}
 
-	logger.Debugf("context has been instantiated with the data-driven template %s", ctx.TemplateFilePath)
+	logger.Debugf("context has been instantiated with the template delimiters %+v", templateDelimiters)
+
+	return templateDelimiters, nil
+}
+
+/*
+RetrievePayload retrieves the data provided by the payload file
+and the given entries, without any data-driven template.
+
+The secrets found in the payload are registered in the logger.
+*/
+func RetrievePayload(ctx gocontext.Context, payload string, templates string) (map[string]interface{}, error) {
+	_, payloadData, err := retrievePayload(ctx, payload, templates)
+	return payloadData, err
+}
 
-	// last but not least, instantiates an orbitPayload which will allow us
-	// to retrieves the data provided by the entries given by the user.
+// retrievePayload instantiates an orbitPayload from the payload file and the given entries,
+// then retrieves its data.
+func retrievePayload(ctx gocontext.Context, payload string, templates string) (*orbitPayload, map[string]interface{}, error) {
 	p := &orbitPayload{}
 
 	if err := p.populateFromFile(""); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := p.populateFromString(payload, templates); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	payloadData, err := p.retrievePayloadData()
+	payloadData, err := p.retrieve(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	ctx.Payload = payloadData
-	logger.Debugf("context has been populated with payload %s", ctx.Payload)
+	return p, payloadData, nil
+}
 
-	ctx.Templates = p.TemplatesEntries
-	logger.Debugf("context has been populated with templates %s", ctx.Templates)
+// retrieve retrieves the data of the payload and registers its secrets in the logger.
+func (p *orbitPayload) retrieve(ctx gocontext.Context) (map[string]interface{}, error) {
+	payloadData, err := p.retrievePayloadData(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if templateDelimiters == nil {
-		ctx.TemplateDelimiters = make([]string, 2)
-	} else if len(templateDelimiters) == 2 {
-		ctx.TemplateDelimiters = templateDelimiters
-		logger.Debugf("context has been instantiated with the template delimiters %+v", ctx.TemplateDelimiters)
-	} else {
-		return nil, OrbitError.NewOrbitErrorf("%d delimiter(s) specified: %+v. Exactly two (left,right) must be specified", len(templateDelimiters), templateDelimiters)
+	// the secrets must be registered before logging anything about the payload.
+	secrets, err := p.retrieveSecrets(payloadData)
+	if err != nil {
+		return nil, err
 	}
 
-	return ctx, nil
+	logger.AddSecrets(secrets...)
+
+	return payloadData, nil
 }
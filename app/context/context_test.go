@@ -1,7 +1,10 @@
 package context
 
 import (
+	gocontext "context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -9,69 +12,117 @@ import (
 // with wrong parameters or no error if the parameters are OK.
 func TestNewOrbitContext(t *testing.T) {
 	// case 1: uses an empty template file path.
-	if _, err := NewOrbitContext("", "", "", nil); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), "", "", "", nil); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 2: uses a non existing template file path.
-	if _, err := NewOrbitContext("non_existing_file", "", "", nil); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), "non_existing_file", "", "", nil); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 3: uses an existing template file path.
 	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
-	if _, err := NewOrbitContext(templateFilePath, "", "", nil); err != nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil); err != nil {
 		t.Error("OrbitContext should have been instantiated!")
 	}
 
 	// case 4: uses a broken payload string.
-	if _, err := NewOrbitContext(templateFilePath, "key", "", nil); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "key", "", nil); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 5: uses a correct payload string.
-	if _, err := NewOrbitContext(templateFilePath, "key,value", "", nil); err != nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "key,value", "", nil); err != nil {
 		t.Error("OrbitContext should have been instantiated!")
 	}
 
 	// case 6: uses a broken payload entry.
 	brokenPayloadEntryFilePath, _ := filepath.Abs("../../_tests/broken-data-source.yml")
-	if _, err := NewOrbitContext(templateFilePath, "key,"+brokenPayloadEntryFilePath, "", nil); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "key,"+brokenPayloadEntryFilePath, "", nil); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 7: uses a correct payload entry.
 	payloadEntryFilePath, _ := filepath.Abs("../../_tests/data-source.yml")
-	if _, err := NewOrbitContext(templateFilePath, "key,"+payloadEntryFilePath, "", nil); err != nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "key,"+payloadEntryFilePath, "", nil); err != nil {
 		t.Error("OrbitContext should have been instantiated!")
 	}
 
 	// case 8: uses a nil template delimiter set
-	if _, err := NewOrbitContext(templateFilePath, "", "", nil); err != nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil); err != nil {
 		t.Error("OrbitContext should have been instantiated!")
 	}
 
 	// case 9: uses a valid, two-element delimiter set
 	templateDelimiters := []string{"a", "b"}
-	if _, err := NewOrbitContext(templateFilePath, "", "", templateDelimiters); err != nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", templateDelimiters); err != nil {
 		t.Error("OrbitContext should have been instantiated!")
 	}
 
 	// case 10: uses an invalid, empty delimiter set
 	templateDelimiters = []string{}
-	if _, err := NewOrbitContext(templateFilePath, "", "", templateDelimiters); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", templateDelimiters); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 11: uses an invalid, one-element delimiter set
 	templateDelimiters = []string{"a"}
-	if _, err := NewOrbitContext(templateFilePath, "", "", templateDelimiters); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", templateDelimiters); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
 
 	// case 12: uses an invalid, three-element delimiter set
 	templateDelimiters = []string{"a", "b", "c"}
-	if _, err := NewOrbitContext(templateFilePath, "", "", templateDelimiters); err == nil {
+	if _, err := NewOrbitContext(gocontext.Background(), templateFilePath, "", "", templateDelimiters); err == nil {
 		t.Error("OrbitContext should not have been instantiated!")
 	}
+
+	// case 13: uses a cancelled context while decoding the payload.
+	cancelled, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err := NewOrbitContext(cancelled, templateFilePath, "key,"+payloadEntryFilePath, "", nil); err == nil {
+		t.Error("OrbitContext should not have been instantiated!")
+	}
+
+	// case 14: uses a template read from Stdin.
+	stdin = strings.NewReader("{{ .Orbit.key }}")
+	defer func() { stdin = os.Stdin }()
+
+	orbitContext, err := NewOrbitContext(gocontext.Background(), StdinFilePath, "key,value", "", nil)
+	if err != nil || string(orbitContext.TemplateContent) != "{{ .Orbit.key }}" {
+		t.Error("OrbitContext should have been instantiated with the template read from Stdin!")
+	}
+}
+
+// Tests if initializing an OrbitContext from a parent shares its payload
+// and merges the given entries over it.
+func TestNewOrbitContextFromParent(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
+	parent, _ := NewOrbitContext(gocontext.Background(), templateFilePath, "launcher,Falcon 9;agency,SpaceX", "", []string{"<<", ">>"})
+
+	// case 1: uses a non existing template file path.
+	if _, err := NewOrbitContextFromParent(gocontext.Background(), parent, "non_existing_file", "", "", nil); err == nil {
+		t.Error("OrbitContext should not have been instantiated!")
+	}
+
+	// case 2: uses entries and delimiters.
+	orbitContext, err := NewOrbitContextFromParent(gocontext.Background(), parent, templateFilePath, "launcher,Falcon Heavy", "", []string{"[[", "]]"})
+	if err != nil {
+		t.Fatal("OrbitContext should have been instantiated!")
+	}
+
+	if orbitContext.Payload["launcher"] != "Falcon Heavy" || orbitContext.Payload["agency"] != "SpaceX" || parent.Payload["launcher"] != "Falcon 9" {
+		t.Errorf("Entries should have been merged over the payload of the parent, got %v!", orbitContext.Payload)
+	}
+
+	if orbitContext.TemplateDelimiters[0] != "[[" {
+		t.Error("Delimiters should have replaced the ones of the parent!")
+	}
+
+	// case 3: uses no entries nor delimiters.
+	orbitContext, _ = NewOrbitContextFromParent(gocontext.Background(), parent, templateFilePath, "", "", nil)
+	if orbitContext.Payload["launcher"] != "Falcon 9" || orbitContext.TemplateDelimiters[0] != "<<" {
+		t.Error("OrbitContext should have shared the payload and the delimiters of the parent!")
+	}
 }
@@ -64,26 +64,25 @@ func (d *orbitYAMLDecoder) decode() (interface{}, error) {
 		return nil, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", d.value, err)
 	}
 
-	var decoded interface{}
-	if err := yaml.Unmarshal(data, &decoded); err != nil {
+	result, err := DecodeYAML(data)
+	if err != nil {
 		return nil, OrbitError.NewOrbitErrorf("unable to decode the YAML file %s. Details:\n%s", d.value, err)
 	}
 
-	var result interface{}
-	cleanup(decoded, &result)
-
 	return result, nil
 }
 
 // decode from orbitTOMLDecoder reads a TOML file and retrieves its data.
 func (d *orbitTOMLDecoder) decode() (interface{}, error) {
-	var decoded interface{}
-	if _, err := toml.DecodeFile(d.value, &decoded); err != nil {
-		return nil, OrbitError.NewOrbitErrorf("unable to decode the TOML file %s. Details:\n%s", d.value, err)
+	data, err := ioutil.ReadFile(d.value)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", d.value, err)
 	}
 
-	var result interface{}
-	cleanup(decoded, &result)
+	result, err := DecodeTOML(data)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to decode the TOML file %s. Details:\n%s", d.value, err)
+	}
 
 	return result, nil
 }
@@ -95,20 +94,22 @@ func (d *orbitJSONDecoder) decode() (interface{}, error) {
 		return nil, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", d.value, err)
 	}
 
-	var decoded interface{}
-	if err := json.Unmarshal(data, &decoded); err != nil {
+	result, err := DecodeJSON(data)
+	if err != nil {
 		return nil, OrbitError.NewOrbitErrorf("unable to decode the JSON file %s. Details:\n%s", d.value, err)
 	}
 
-	var result interface{}
-	cleanup(decoded, &result)
-
 	return result, nil
 }
 
 // decode from orbitEnvFileDecoder reads a .env file and retrieves its data.
 func (d *orbitEnvFileDecoder) decode() (interface{}, error) {
-	result, err := godotenv.Read(d.value)
+	data, err := ioutil.ReadFile(d.value)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", d.value, err)
+	}
+
+	result, err := DecodeDotenv(data)
 	if err != nil {
 		return nil, OrbitError.NewOrbitErrorf("unable to decode the .env file %s. Details:\n%s", d.value, err)
 	}
@@ -116,6 +117,50 @@ func (d *orbitEnvFileDecoder) decode() (interface{}, error) {
 	return result, nil
 }
 
+// DecodeYAML decodes the given YAML data.
+func DecodeYAML(data []byte) (interface{}, error) {
+	var decoded interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	cleanup(decoded, &result)
+
+	return result, nil
+}
+
+// DecodeTOML decodes the given TOML data.
+func DecodeTOML(data []byte) (interface{}, error) {
+	var decoded interface{}
+	if _, err := toml.Decode(string(data), &decoded); err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	cleanup(decoded, &result)
+
+	return result, nil
+}
+
+// DecodeJSON decodes the given JSON data.
+func DecodeJSON(data []byte) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	cleanup(decoded, &result)
+
+	return result, nil
+}
+
+// DecodeDotenv decodes the given .env data.
+func DecodeDotenv(data []byte) (map[string]string, error) {
+	return godotenv.Unmarshal(string(data))
+}
+
 /*
 cleanup parses an interface recursively to find and update
 map[interface{}]interface{} to map[string]interface{}.
@@ -1,8 +1,13 @@
 package context
 
 import (
+	gocontext "context"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	OrbitError "github.com/gulien/orbit/app/error"
@@ -15,6 +20,9 @@ import (
 // default payload file path.
 const payloadFilePath = "orbit-payload.yml"
 
+// minSecretLength is the minimum length of a value to mask, so that short values are not masked across all output.
+const minSecretLength = 4
+
 type (
 	// orbitPayload contains the various entries provided by the user.
 	orbitPayload struct {
@@ -23,6 +31,10 @@ type (
 
 		// TemplatesEntries is a simple array of string.
 		TemplatesEntries []string `yaml:"templates,omitempty"`
+
+		// SecretsPatterns is an array of regex patterns matching the names
+		// of the data and environment variables which are secrets.
+		SecretsPatterns []string `yaml:"secrets,omitempty"`
 	}
 
 	// orbitPayloadEntry is an entry from a file or from a string.
@@ -32,6 +44,9 @@ type (
 
 		// Value is a raw data or a file path.
 		Value string `yaml:"value"`
+
+		// Secret allows to hide the data of the entry from the logs.
+		Secret bool `yaml:"secret,omitempty"`
 	}
 )
 
@@ -102,10 +117,15 @@ func (p *orbitPayload) populateFromString(payload string, templates string) erro
 
 // retrievePayloadData parses all the payload entries from the instance of orbitPayload
 // to retrieve the data which will be applied to a data-driven template.
-func (p *orbitPayload) retrievePayloadData() (map[string]interface{}, error) {
+// Stops as soon as the given context is done.
+func (p *orbitPayload) retrievePayloadData(ctx gocontext.Context) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	for _, payloadEntry := range p.PayloadEntries {
+		if err := ctx.Err(); err != nil {
+			return nil, OrbitError.NewOrbitErrorf("unable to decode the payload entry %s. Details:\n%s", payloadEntry.Key, err)
+		}
+
 		d := getDecoder(payloadEntry.Value)
 
 		value, err := d.decode()
@@ -119,6 +139,134 @@ func (p *orbitPayload) retrievePayloadData() (map[string]interface{}, error) {
 	return result, nil
 }
 
+/*
+retrieveSecrets returns the values which should never be displayed:
+
+- the data of the payload entries marked as secret.
+- the data and the environment variables whose names match a secret pattern.
+*/
+func (p *orbitPayload) retrieveSecrets(payloadData map[string]interface{}) ([]string, error) {
+	patterns := make([]*regexp.Regexp, len(p.SecretsPatterns))
+	for index, pattern := range p.SecretsPatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, OrbitError.NewOrbitErrorf("unable to compile the secret pattern %s. Details:\n%s", pattern, err)
+		}
+
+		patterns[index] = compiled
+	}
+
+	var secrets []string
+	for _, payloadEntry := range p.PayloadEntries {
+		if payloadEntry.Secret {
+			secrets = append(secrets, collectValues(payloadData[payloadEntry.Key])...)
+		}
+	}
+
+	if len(patterns) > 0 {
+		secrets = append(secrets, collectMatchingValues(payloadData, patterns)...)
+
+		for _, variable := range os.Environ() {
+			pair := strings.SplitN(variable, "=", 2)
+			if len(pair) == 2 && matchAny(pair[0], patterns) {
+				secrets = append(secrets, pair[1])
+			}
+		}
+	}
+
+	return filterSecrets(secrets), nil
+}
+
+/*
+filterSecrets removes the values which are too short, the booleans and the numbers
+(e.g. a port or a flag of a secret entry), as masking them would mask every occurrence
+of these common strings in the output.
+*/
+func filterSecrets(values []string) []string {
+	var secrets []string
+	for _, value := range values {
+		if len(value) < minSecretLength {
+			continue
+		}
+
+		if _, err := strconv.ParseBool(value); err == nil {
+			continue
+		}
+
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			continue
+		}
+
+		secrets = append(secrets, value)
+	}
+
+	return secrets
+}
+
+// collectValues returns the scalar values of a data recursively.
+func collectValues(data interface{}) []string {
+	var values []string
+
+	switch data := data.(type) {
+	case nil:
+	case map[string]interface{}:
+		for _, v := range data {
+			values = append(values, collectValues(v)...)
+		}
+	case map[string]string:
+		for _, v := range data {
+			values = append(values, v)
+		}
+	case []interface{}:
+		for _, v := range data {
+			values = append(values, collectValues(v)...)
+		}
+	default:
+		values = append(values, fmt.Sprintf("%v", data))
+	}
+
+	return values
+}
+
+// collectMatchingValues returns the scalar values of a data whose names match at least one pattern.
+func collectMatchingValues(data interface{}, patterns []*regexp.Regexp) []string {
+	var values []string
+
+	switch data := data.(type) {
+	case map[string]interface{}:
+		for k, v := range data {
+			if matchAny(k, patterns) {
+				values = append(values, collectValues(v)...)
+			} else {
+				values = append(values, collectMatchingValues(v, patterns)...)
+			}
+		}
+	case map[string]string:
+		for k, v := range data {
+			if matchAny(k, patterns) {
+				values = append(values, v)
+			}
+		}
+	case []interface{}:
+		for _, v := range data {
+			values = append(values, collectMatchingValues(v, patterns)...)
+		}
+	}
+
+	return values
+}
+
+// matchAny returns true if the name matches at least one pattern.
+func matchAny(name string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getDecoder returns the correct decoder for a given value.
 func getDecoder(value string) orbitDecoder {
 	if !helpers.FileExists(value) {
@@ -1,6 +1,8 @@
 package context
 
 import (
+	gocontext "context"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -59,7 +61,7 @@ func TestRetrievePayloadData(t *testing.T) {
 	brokenDataSourceFilePath, _ := filepath.Abs("../../_tests/broken-data-source.yml")
 	p := &orbitPayload{}
 	p.populateFromString("key,"+brokenDataSourceFilePath, "")
-	if _, err := p.retrievePayloadData(); err == nil {
+	if _, err := p.retrievePayloadData(gocontext.Background()); err == nil {
 		t.Error("orbitPayload should not have been hable to retrieve data!")
 	}
 
@@ -67,11 +69,56 @@ func TestRetrievePayloadData(t *testing.T) {
 	dataSourceFilePath, _ := filepath.Abs("../../_tests/data-source.yml")
 	p = &orbitPayload{}
 	p.populateFromString("key,"+dataSourceFilePath, "")
-	if _, err := p.retrievePayloadData(); err != nil {
+	if _, err := p.retrievePayloadData(gocontext.Background()); err != nil {
 		t.Error("orbitPayload should have been hable to retrieve data!")
 	}
 }
 
+// Tests if retrieving secrets from an orbitPayload returns the expected values.
+func TestRetrieveSecrets(t *testing.T) {
+	envFilePath, _ := filepath.Abs("../../_tests/.env")
+
+	// case 1: uses a broken secret pattern.
+	p := &orbitPayload{SecretsPatterns: []string{"("}}
+	if _, err := p.retrieveSecrets(nil); err == nil {
+		t.Error("orbitPayload should not have been able to retrieve secrets!")
+	}
+
+	// case 2: uses a payload entry marked as secret.
+	p = &orbitPayload{PayloadEntries: []*orbitPayloadEntry{{Key: "token", Value: "s3cr3t", Secret: true}}}
+	data, _ := p.retrievePayloadData(gocontext.Background())
+	secrets, err := p.retrieveSecrets(data)
+	if err != nil || !reflect.DeepEqual(secrets, []string{"s3cr3t"}) {
+		t.Error("orbitPayload should have retrieved the data of the payload entry marked as secret!")
+	}
+
+	// case 3: uses a secret pattern matching a name from a .env file.
+	p = &orbitPayload{SecretsPatterns: []string{"^SPACEX"}}
+	p.populateFromString("Values,"+envFilePath, "")
+	data, _ = p.retrievePayloadData(gocontext.Background())
+	secrets, err = p.retrieveSecrets(data)
+	if err != nil || !reflect.DeepEqual(secrets, []string{"Falcon 9, Falcon Heavy"}) {
+		t.Error("orbitPayload should have retrieved the data matching the secret pattern!")
+	}
+
+	// case 4: uses a secret pattern matching an environment variable.
+	os.Setenv("ORBIT_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("ORBIT_TEST_TOKEN")
+	p = &orbitPayload{SecretsPatterns: []string{"^ORBIT_TEST_TOKEN$"}}
+	secrets, err = p.retrieveSecrets(nil)
+	if err != nil || !reflect.DeepEqual(secrets, []string{"t0k3n"}) {
+		t.Error("orbitPayload should have retrieved the environment variable matching the secret pattern!")
+	}
+
+	// case 5: uses a secret entry containing short values, booleans and numbers.
+	secrets, err = (&orbitPayload{PayloadEntries: []*orbitPayloadEntry{{Key: "registry", Secret: true}}}).retrieveSecrets(map[string]interface{}{
+		"registry": map[string]interface{}{"port": 80, "tls": true, "id": "1", "ratio": "0.5", "user": "bot", "password": "p4ssw0rd"},
+	})
+	if err != nil || !reflect.DeepEqual(secrets, []string{"p4ssw0rd"}) {
+		t.Errorf("orbitPayload should only have retrieved the values worth masking, got %v!", secrets)
+	}
+}
+
 // Tests if for a given value the function getDecoder returns a correct
 // instance of decoder.
 func TestGetDecoder(t *testing.T) {
@@ -0,0 +1,78 @@
+package context
+
+import (
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+/*
+ParseOverrides parses values given by the user in the following format:
+key=value. A key may contain dots to override a nested value (e.g. Values.version=2),
+but a key may not be given both a value and nested values.
+
+Returns the values as a map which may be merged over the payload.
+*/
+func ParseOverrides(values []string) (map[string]interface{}, error) {
+	overrides := make(map[string]interface{})
+
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, OrbitError.NewOrbitErrorf("unable to process the value %s. Expected format is key=value", value)
+		}
+
+		keys := strings.Split(parts[0], ".")
+		current := overrides
+		for index, key := range keys[:len(keys)-1] {
+			next, ok := current[key].(map[string]interface{})
+			if !ok && current[key] != nil {
+				return nil, OrbitError.NewOrbitErrorf("unable to process the value %s. Key %s may not be given both a value and nested values", value, strings.Join(keys[:index+1], "."))
+			}
+
+			if !ok {
+				next = make(map[string]interface{})
+				current[key] = next
+			}
+
+			current = next
+		}
+
+		// a key given a value would replace the nested values given to it.
+		if _, ok := current[keys[len(keys)-1]].(map[string]interface{}); ok {
+			return nil, OrbitError.NewOrbitErrorf("unable to process the value %s. Key %s may not be given both a value and nested values", value, parts[0])
+		}
+
+		current[keys[len(keys)-1]] = parts[1]
+	}
+
+	return overrides, nil
+}
+
+/*
+MergeData merges the given maps recursively into a new map: the values
+of a map take precedence over the values of the previous maps.
+
+The given maps are not modified.
+*/
+func MergeData(maps ...map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	for _, m := range maps {
+		for key, value := range m {
+			value = cleanupMapValue(value)
+
+			previous, isMap := result[key].(map[string]interface{})
+			next, isNextMap := value.(map[string]interface{})
+			if isMap && isNextMap {
+				result[key] = MergeData(previous, next)
+			} else if isNextMap {
+				result[key] = MergeData(next)
+			} else {
+				result[key] = value
+			}
+		}
+	}
+
+	return result
+}
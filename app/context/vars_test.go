@@ -0,0 +1,85 @@
+package context
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests if parsing the values given by the user throws an error
+// with a wrong format or returns the expected map.
+func TestParseOverrides(t *testing.T) {
+	// case 1: uses a value without key.
+	if _, err := ParseOverrides([]string{"=Falcon 9"}); err == nil {
+		t.Error("Value without key should not have been parsed!")
+	}
+
+	// case 2: uses a value without "=".
+	if _, err := ParseOverrides([]string{"launcher"}); err == nil {
+		t.Error("Value without \"=\" should not have been parsed!")
+	}
+
+	// case 3: uses simple and nested keys.
+	overrides, err := ParseOverrides([]string{"launcher=Falcon 9", "Values.agency=SpaceX", "Values.crew=a=b"})
+	if err != nil {
+		t.Error("Values should have been parsed!")
+	}
+
+	expected := map[string]interface{}{
+		"launcher": "Falcon 9",
+		"Values": map[string]interface{}{
+			"agency": "SpaceX",
+			"crew":   "a=b",
+		},
+	}
+
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Values should have been %v, got %v!", expected, overrides)
+	}
+
+	// case 4: uses a key given a value, then nested values.
+	if _, err := ParseOverrides([]string{"launcher=Falcon 9", "launcher.stages=2"}); err == nil {
+		t.Error("Key given both a value and nested values should not have been parsed!")
+	}
+
+	// case 5: uses a key given nested values, then a value.
+	if _, err := ParseOverrides([]string{"launcher.stages=2", "launcher=Falcon 9"}); err == nil {
+		t.Error("Key given both nested values and a value should not have been parsed!")
+	}
+}
+
+// Tests if merging maps gives precedence to the last maps
+// and does not modify the given maps.
+func TestMergeData(t *testing.T) {
+	payload := map[string]interface{}{
+		"launcher": "Ariane 5",
+		"Values": map[string]interface{}{
+			"agency": "ESA",
+			"site":   "Kourou",
+		},
+	}
+
+	vars := map[string]interface{}{
+		"launcher": "Falcon 9",
+		"Values": map[interface{}]interface{}{
+			"agency": "SpaceX",
+		},
+	}
+
+	data := MergeData(payload, vars, map[string]interface{}{"crew": "Dragon"})
+	expected := map[string]interface{}{
+		"launcher": "Falcon 9",
+		"crew":     "Dragon",
+		"Values": map[string]interface{}{
+			"agency": "SpaceX",
+			"site":   "Kourou",
+		},
+	}
+
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Merged data should have been %v, got %v!", expected, data)
+	}
+
+	if payload["Values"].(map[string]interface{})["agency"] != "ESA" {
+		t.Error("Given maps should not have been modified!")
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"os"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// explainCmd is the instance of explain command.
+	explainCmd = &cobra.Command{
+		Use:           "explain",
+		Short:         "Prints the definition of a task defined in a configuration file",
+		Long:          "Prints the definition of a task defined in a configuration file: where it comes from, its commands once rendered and the tasks it calls.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          explain,
+	}
+)
+
+// init initializes an explainCmd instance and adds it to the RootCmd.
+func init() {
+	RootCmd.AddCommand(explainCmd)
+}
+
+// explain prints the definition of a task.
+func explain(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return OrbitError.NewOrbitErrorf("%d task(s) given: %+v. Exactly one task must be specified", len(args), args)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	r, err := newOrbitRunner(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.Explain(ctx, os.Stdout, args[0])
+}
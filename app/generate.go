@@ -1,8 +1,16 @@
 package app
 
 import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
 	"github.com/gulien/orbit/app/generator"
+	"github.com/gulien/orbit/app/logger"
 
 	"github.com/spf13/cobra"
 )
@@ -13,11 +21,47 @@ var (
 	// templateDelimiters is the optional (overriding) pair of template delimiters.
 	templateDelimiters []string
 
+	// fromDirPath is the path of a template directory.
+	fromDirPath string
+
+	// toDirPath is the path of the directory generated from the template directory.
+	toDirPath string
+
+	// rawPatterns are the patterns of the files of the template directory copied verbatim.
+	rawPatterns []string
+
+	// ignorePatterns are the patterns of the files of the template directory which are skipped.
+	ignorePatterns []string
+
+	// all generates the files listed by the generate section of the configuration file if true.
+	all bool
+
+	// check fails if an output file is not up to date instead of writing it if true.
+	check bool
+
+	// diff prints the unified diff of an output file instead of writing it if true.
+	diff bool
+
+	// fileMode is the octal mode of the output files (e.g. 0600).
+	fileMode string
+
+	// inPlaceFilePath is the path of a file whose managed block is replaced by the result.
+	inPlaceFilePath string
+
+	// blockName is the name of the managed block.
+	blockName string
+
+	// blockComment is the prefix of the lines delimiting the managed block.
+	blockComment string
+
+	// sandboxDirPath is the directory the data-driven templates may not read outside of.
+	sandboxDirPath string
+
 	// generateCmd is the instance of generate command.
 	generateCmd = &cobra.Command{
 		Use:           "generate",
 		Short:         "Generates a file according to a data-driven template",
-		Long:          "Generates a file according to a data-driven template.",
+		Long:          "Generates a file according to a data-driven template, or a directory according to a template directory.",
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE:          generate,
@@ -28,6 +72,18 @@ var (
 func init() {
 	generateCmd.Flags().StringVarP(&outputFilePath, "output", "o", "", "specify the output file which will be generated from a data-driven template")
 	generateCmd.Flags().StringSliceVar(&templateDelimiters, "delimiters", make([]string, 2), "optionally specify template delimiters")
+	generateCmd.Flags().StringVar(&fromDirPath, "from", "", "specify a template directory whose files and names are data-driven templates")
+	generateCmd.Flags().StringVar(&toDirPath, "to", "", "specify the directory which will be generated from the template directory")
+	generateCmd.Flags().StringSliceVar(&rawPatterns, "raw", nil, "specify the patterns of the files of the template directory which are copied verbatim (e.g. *.png,assets)")
+	generateCmd.Flags().StringSliceVar(&ignorePatterns, "ignore", nil, "specify the patterns of the files of the template directory which are skipped")
+	generateCmd.Flags().BoolVar(&all, "all", false, "generate the files listed by the generate section of the configuration file (orbit.yml by default)")
+	generateCmd.Flags().BoolVar(&check, "check", false, "exit with an error if an output file is not up to date, without writing it")
+	generateCmd.Flags().BoolVar(&diff, "diff", false, "print the unified diff of an output file instead of writing it")
+	generateCmd.Flags().StringVar(&fileMode, "mode", "", "specify the octal mode of the output files (e.g. 0600), the mode of an existing file is kept by default")
+	generateCmd.Flags().StringVar(&inPlaceFilePath, "in-place", "", "specify a file whose managed block (see --block) is replaced by the result, leaving the rest of the file intact")
+	generateCmd.Flags().StringVar(&blockName, "block", "", "specify the name of the managed block, delimited by the lines \"# BEGIN orbit:name\" and \"# END orbit:name\"")
+	generateCmd.Flags().StringVar(&blockComment, "comment", generator.DefaultBlockComment, "specify the prefix of the lines delimiting the managed block (e.g. //)")
+	generateCmd.Flags().StringVar(&sandboxDirPath, "sandbox", "", "prevent the file system functions of the data-driven templates from reading outside of the given directory (e.g. .)")
 	RootCmd.AddCommand(generateCmd)
 }
 
@@ -37,18 +93,205 @@ generate transforms a data-driven template to a resulting file.
 If no output file is given, prints the result to Stdout.
 */
 func generate(cmd *cobra.Command, args []string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	// the logs must not be mixed with a result printed to Stdout.
+	logger.SetOutput(os.Stderr)
+
+	if (inPlaceFilePath != "" || blockName != "") && (all || fromDirPath != "" || toDirPath != "") {
+		return OrbitError.NewOrbitErrorf("--in-place and --block flags may not be used with --all, --from or --to flags")
+	}
+
+	if all {
+		return generateAll(ctx)
+	}
+
+	if fromDirPath != "" || toDirPath != "" {
+		return scaffold(ctx)
+	}
+
+	if inPlaceFilePath != "" || blockName != "" {
+		if inPlaceFilePath == "" || blockName == "" {
+			return OrbitError.NewOrbitErrorf("both --in-place and --block flags are required to replace a managed block")
+		}
+
+		if outputFilePath != "" {
+			return OrbitError.NewOrbitErrorf("--in-place flag may not be used with --output flag")
+		}
+	}
+
 	// first, let's instantiate our Orbit context.
-	ctx, err := context.NewOrbitContext(templateFilePath, payload, templates, templateDelimiters)
+	orbitContext, err := context.NewOrbitContext(ctx, templateFilePath, payload, templates, templateDelimiters)
+	if err != nil {
+		return err
+	}
+
+	mode, err := parseFileMode()
 	if err != nil {
 		return err
 	}
 
 	// then retrieves the data from the template file.
-	g := generator.NewOrbitGenerator(ctx)
-	data, err := g.Execute()
+	g := generator.NewOrbitGenerator(orbitContext)
+	g.SetFileMode(mode)
+	if err := g.SetSandbox(sandboxDirPath); err != nil {
+		return err
+	}
+
+	// the files generated by the output function are relative to the output file.
+	if inPlaceFilePath != "" {
+		g.SetOutputFile(inPlaceFilePath)
+	} else {
+		g.SetOutputFile(outputFilePath)
+	}
+
+	data, err := g.Execute(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the result replaces the managed block of the file, which becomes the output file.
+	if inPlaceFilePath != "" {
+		content, err := generator.ReplaceBlock(inPlaceFilePath, blockName, blockComment, data.Bytes())
+		if err != nil {
+			return err
+		}
+
+		outputFilePath = inPlaceFilePath
+		data.Reset()
+		data.Write(content)
+	}
+
+	if check || diff {
+		// the files generated by the output function are compared too.
+		outputs := g.Outputs()
+		if outputFilePath != "" {
+			outputs = append([]*generator.OrbitOutput{{Path: outputFilePath, Data: data}}, outputs...)
+		}
+
+		if len(outputs) == 0 {
+			return OrbitError.NewOrbitErrorf("--check and --diff flags require an output file")
+		}
+
+		return compare(generator.DiffOutputs(outputs))
+	}
+
+	if err := g.Flush(outputFilePath, data); err != nil {
+		return err
+	}
+
+	return g.FlushOutputs()
+}
+
+/*
+compare prints the given unified diff if the diff flag is set,
+and returns an error listing the outdated files if the check flag is set.
+*/
+func compare(unifiedDiff string, outdated []string, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if diff {
+		fmt.Print(unifiedDiff)
+	}
+
+	if check && len(outdated) > 0 {
+		return OrbitError.NewOrbitErrorf("the following file(s) are not up to date:\n%s", strings.Join(outdated, "\n"))
+	}
+
+	return nil
+}
+
+// scaffold transforms a template directory to a resulting directory.
+func scaffold(ctx gocontext.Context) error {
+	if fromDirPath == "" || toDirPath == "" {
+		return OrbitError.NewOrbitErrorf("both --from and --to flags are required to generate a directory")
+	}
+
+	if templateFilePath != "" || outputFilePath != "" {
+		return OrbitError.NewOrbitErrorf("--from and --to flags may not be used with --file and --output flags")
+	}
+
+	if check || diff {
+		return OrbitError.NewOrbitErrorf("--check and --diff flags may not be used with --from and --to flags")
+	}
+
+	orbitContext, err := context.NewOrbitContext(ctx, fromDirPath, payload, templates, templateDelimiters)
+	if err != nil {
+		return err
+	}
+
+	g := generator.NewOrbitGenerator(orbitContext)
+	if err := g.SetSandbox(sandboxDirPath); err != nil {
+		return err
+	}
+
+	_, err = g.Scaffold(ctx, toDirPath, rawPatterns, ignorePatterns)
+	return err
+}
+
+/*
+generateAll generates the files listed by the generate section of the configuration file,
+decoding the shared payload only once.
+
+Prints the paths of the files which have changed.
+*/
+func generateAll(ctx gocontext.Context) error {
+	if outputFilePath != "" || fromDirPath != "" || toDirPath != "" {
+		return OrbitError.NewOrbitErrorf("--all flag may not be used with --output, --from and --to flags")
+	}
+
+	manifestFilePath := templateFilePath
+	if manifestFilePath == "" {
+		manifestFilePath = orbitFilePath
+	}
+
+	m, err := generator.NewOrbitManifest(manifestFilePath)
+	if err != nil {
+		return err
+	}
+
+	mode, err := parseFileMode()
+	if err != nil {
+		return err
+	}
+
+	m.SetFileMode(mode)
+	m.SetSandbox(sandboxDirPath)
+
+	orbitContext, err := context.NewOrbitContext(ctx, manifestFilePath, payload, templates, templateDelimiters)
 	if err != nil {
 		return err
 	}
 
-	return g.Flush(outputFilePath, data)
+	if check || diff {
+		return compare(m.Diff(ctx, orbitContext))
+	}
+
+	changed, err := m.Generate(ctx, orbitContext)
+	for _, path := range changed {
+		fmt.Println(path)
+	}
+
+	if err == nil {
+		logger.Infof("%d of %d file(s) changed", len(changed), len(m.Entries))
+	}
+
+	return err
+}
+
+// parseFileMode parses the octal mode given by the user, zero if none.
+func parseFileMode() (os.FileMode, error) {
+	if fileMode == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(fileMode, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, OrbitError.NewOrbitErrorf("mode %s is not valid, expected an octal mode such as 0600", fileMode)
+	}
+
+	return os.FileMode(mode), nil
 }
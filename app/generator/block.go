@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+// DefaultBlockComment is the default prefix of the lines delimiting a managed block.
+const DefaultBlockComment = "#"
+
+/*
+ReplaceBlock returns the content of the given file where the managed block of the given name
+is replaced by the given data. A managed block is delimited by the following lines:
+
+	# BEGIN orbit:name
+	...
+	# END orbit:name
+
+where # is the given comment prefix. If the file does not contain the block, it is appended
+to the content of the file. Everything outside of the block is left byte-for-byte intact.
+The block uses the line ending of the file (\r\n if its first line ends with it, \n otherwise).
+
+A missing file is considered as empty.
+*/
+func ReplaceBlock(filePath string, name string, comment string, data []byte) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return nil, OrbitError.NewOrbitErrorf("block name %q is not valid, it must not be empty nor contain spaces", name)
+	}
+
+	if comment == "" {
+		comment = DefaultBlockComment
+	}
+
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", filePath, err)
+	}
+
+	result, err := replaceBlock(content, name, comment, data)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to replace the block %s of the file %s. Details:\n%s", name, filePath, err)
+	}
+
+	return result, nil
+}
+
+// replaceBlock replaces the managed block of the given name in the given content.
+func replaceBlock(content []byte, name string, comment string, data []byte) ([]byte, error) {
+	begin := comment + " BEGIN orbit:" + name
+	end := comment + " END orbit:" + name
+
+	// beginStart and beginEnd are the offsets of the beginning marker line, endStart of the ending one.
+	beginStart, beginEnd, endStart := -1, -1, -1
+	for offset := 0; offset < len(content); {
+		lineEnd := bytes.IndexByte(content[offset:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += offset + 1
+		}
+
+		line := strings.TrimSpace(string(content[offset:lineEnd]))
+		switch {
+		case line == begin && beginStart >= 0:
+			return nil, OrbitError.NewOrbitErrorf("line %q is found more than once", begin)
+		case line == begin:
+			beginStart, beginEnd = offset, lineEnd
+		case line == end && beginStart < 0:
+			return nil, OrbitError.NewOrbitErrorf("line %q is found before line %q", end, begin)
+		case line == end && endStart < 0:
+			endStart = offset
+		case line == end:
+			return nil, OrbitError.NewOrbitErrorf("line %q is found more than once", end)
+		}
+
+		offset = lineEnd
+	}
+
+	if beginStart >= 0 && endStart < 0 {
+		return nil, OrbitError.NewOrbitErrorf("line %q is missing", end)
+	}
+
+	eol := lineEnding(content)
+
+	block := bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	if eol != "\n" {
+		block = bytes.Replace(block, []byte("\n"), []byte(eol), -1)
+	}
+
+	if len(block) > 0 && !bytes.HasSuffix(block, []byte("\n")) {
+		block = append(block, eol...)
+	}
+
+	var result bytes.Buffer
+
+	// the block is missing, let's append it.
+	if beginStart < 0 {
+		result.Write(content)
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			result.WriteString(eol)
+		}
+
+		result.WriteString(begin + eol)
+		result.Write(block)
+		result.WriteString(end + eol)
+
+		return result.Bytes(), nil
+	}
+
+	// the markers are kept as is.
+	result.Write(content[:beginEnd])
+	result.Write(block)
+	result.Write(content[endStart:])
+
+	return result.Bytes(), nil
+}
+
+// lineEnding returns the line ending of the given content: \r\n if its first line ends with it, \n otherwise.
+func lineEnding(content []byte) string {
+	if index := bytes.IndexByte(content, '\n'); index > 0 && content[index-1] == '\r' {
+		return "\r\n"
+	}
+
+	return "\n"
+}
@@ -0,0 +1,276 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+const (
+	// diffContext is the number of unchanged lines around the changes of a unified diff.
+	diffContext = 3
+
+	// diffMaxEdits is the maximum number of removed and added lines of a minimal diff.
+	diffMaxEdits = 2000
+)
+
+// diffEdit is a line of a diff.
+type diffEdit struct {
+	// op is ' ' for an unchanged line, '-' for a removed line and '+' for an added line.
+	op byte
+
+	// line is the content of the line, including its line break if any.
+	line string
+}
+
+/*
+Diff returns the unified diff between the content of the given output file
+and the given data, or an empty string if the file is up to date.
+
+A missing output file is considered as empty.
+*/
+func Diff(outputPath string, data []byte) (string, error) {
+	current, err := ioutil.ReadFile(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", OrbitError.NewOrbitErrorf("unable to read the output file %s. Details:\n%s", outputPath, err)
+	}
+
+	return unifiedDiff(outputPath, current, data), nil
+}
+
+/*
+DiffOutputs returns the unified diff between the given files and their content on disk,
+and the paths of the files which are not up to date.
+*/
+func DiffOutputs(outputs []*OrbitOutput) (string, []string, error) {
+	var (
+		diffs    bytes.Buffer
+		outdated []string
+	)
+
+	for _, output := range outputs {
+		diff, err := Diff(output.Path, output.Data.Bytes())
+		if err != nil {
+			return diffs.String(), outdated, err
+		}
+
+		if diff != "" {
+			diffs.WriteString(diff)
+			outdated = append(outdated, output.Path)
+		}
+	}
+
+	return diffs.String(), outdated, nil
+}
+
+// unifiedDiff returns the unified diff between the given contents of the given file, or an empty string if they are equal.
+func unifiedDiff(name string, from []byte, to []byte) string {
+	if bytes.Equal(from, to) {
+		return ""
+	}
+
+	edits := diffLines(splitLines(from), splitLines(to))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+
+	// aLines and bLines are the numbers of lines of each content before each edit.
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for index, e := range edits {
+		aLines[index+1], bLines[index+1] = aLines[index], bLines[index]
+		if e.op != '+' {
+			aLines[index+1]++
+		}
+
+		if e.op != '-' {
+			bLines[index+1]++
+		}
+	}
+
+	for index := 0; index < len(edits); {
+		// finds the next change...
+		for index < len(edits) && edits[index].op == ' ' {
+			index++
+		}
+
+		if index == len(edits) {
+			break
+		}
+
+		// ...and the last change of its hunk, merging the changes separated by few unchanged lines.
+		start := maxInt(index-diffContext, 0)
+		last := index
+		for next := index + 1; next < len(edits) && next-last-1 <= 2*diffContext; next++ {
+			if edits[next].op != ' ' {
+				last = next
+			}
+		}
+
+		end := minInt(last+1+diffContext, len(edits))
+
+		aStart, aCount := aLines[start], aLines[end]-aLines[start]
+		bStart, bCount := bLines[start], bLines[end]-bLines[start]
+		if aCount > 0 {
+			aStart++
+		}
+
+		if bCount > 0 {
+			bStart++
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		index = end
+	}
+
+	return out.String()
+}
+
+// splitLines splits the given content into lines, keeping their line breaks.
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		index := bytes.IndexByte(content, '\n')
+		if index < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+
+		lines = append(lines, string(content[:index+1]))
+		content = content[index+1:]
+	}
+
+	return lines
+}
+
+/*
+diffLines returns the shortest edit script from a to b, computed with the Myers algorithm.
+
+If the contents are too different, returns an edit script which removes all the lines
+of a then adds all the lines of b, so that the memory usage stays reasonable.
+*/
+func diffLines(a []string, b []string) []diffEdit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace contains the furthest reaching paths (for k from -d-1 to d+1) before each step d.
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > diffMaxEdits {
+			return replaceLines(a, b)
+		}
+
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walks the trace backwards to build the edit script.
+	var (
+		edits []diffEdit
+		x, y  = n, m
+	)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		// the paths of the step d are stored from k = -d-1.
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, diffEdit{op: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, diffEdit{op: '+', line: b[y-1]})
+			} else {
+				edits = append(edits, diffEdit{op: '-', line: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// replaceLines returns the edit script which removes all the lines of a then adds all the lines of b.
+func replaceLines(a []string, b []string) []diffEdit {
+	edits := make([]diffEdit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, diffEdit{op: '-', line: line})
+	}
+
+	for _, line := range b {
+		edits = append(edits, diffEdit{op: '+', line: line})
+	}
+
+	return edits
+}
+
+// minInt returns the smallest of the given integers.
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the largest of the given integers.
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+fromYaml decodes the given YAML document.
+
+This function is available in a data-driven template by using "fromYaml".
+*/
+func fromYaml(data string) (interface{}, error) {
+	result, err := context.DecodeYAML([]byte(data))
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to decode the YAML data. Details:\n%s", err)
+	}
+
+	return result, nil
+}
+
+/*
+toYaml encodes the given value as a YAML document, without its trailing line break
+so that it may be piped (e.g. to nindent).
+
+This function is available in a data-driven template by using "toYaml":
+
+	{{ .Orbit.labels | toYaml | nindent 4 }}
+*/
+func toYaml(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to encode the YAML data. Details:\n%s", err)
+	}
+
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+/*
+fromJson decodes the given JSON document.
+
+This function is available in a data-driven template by using "fromJson".
+*/
+func fromJson(data string) (interface{}, error) {
+	result, err := context.DecodeJSON([]byte(data))
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to decode the JSON data. Details:\n%s", err)
+	}
+
+	return result, nil
+}
+
+/*
+fromToml decodes the given TOML document.
+
+This function is available in a data-driven template by using "fromToml".
+*/
+func fromToml(data string) (interface{}, error) {
+	result, err := context.DecodeTOML([]byte(data))
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to decode the TOML data. Details:\n%s", err)
+	}
+
+	return result, nil
+}
+
+/*
+toToml encodes the given dictionary as a TOML document, without its trailing line break.
+
+This function is available in a data-driven template by using "toToml".
+*/
+func toToml(value interface{}) (string, error) {
+	var data bytes.Buffer
+	if err := toml.NewEncoder(&data).Encode(value); err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to encode the TOML data. Details:\n%s", err)
+	}
+
+	return strings.TrimSuffix(data.String(), "\n"), nil
+}
+
+/*
+fromDotenv decodes the given .env document.
+
+This function is available in a data-driven template by using "fromDotenv".
+*/
+func fromDotenv(data string) (map[string]interface{}, error) {
+	decoded, err := context.DecodeDotenv([]byte(data))
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to decode the .env data. Details:\n%s", err)
+	}
+
+	// the values are stored as interfaces to allow the use of the dict functions from Sprig library.
+	result := make(map[string]interface{}, len(decoded))
+	for key, value := range decoded {
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+/*
+toDotenv encodes the given dictionary as a .env document, with one sorted KEY="VALUE" line per entry.
+The values must be scalars: a nested dictionary or list may be flattened beforehand.
+
+This function is available in a data-driven template by using "toDotenv":
+
+	{{ .Orbit.env | toDotenv }}
+*/
+func toDotenv(value map[string]interface{}) (string, error) {
+	env := make(map[string]string, len(value))
+	for key, v := range value {
+		switch v.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			return "", OrbitError.NewOrbitErrorf("unable to encode the .env data: the value of %s is not a scalar", key)
+		case nil:
+			env[key] = ""
+		default:
+			env[key] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	data, err := godotenv.Marshal(env)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to encode the .env data. Details:\n%s", err)
+	}
+
+	return data, nil
+}
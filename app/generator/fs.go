@@ -0,0 +1,305 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+/*
+orbitFS provides the file system functions of a data-driven template.
+
+A relative path given to these functions is resolved relative to the directory
+of the data-driven template.
+*/
+type orbitFS struct {
+	// dir is the directory of the data-driven template.
+	dir string
+
+	// root is the directory the functions may not read outside of, empty if none.
+	root string
+}
+
+// newOrbitFS creates an instance of orbitFS for the given data-driven template.
+func newOrbitFS(templateFilePath string) *orbitFS {
+	dir := templateFilePath
+	switch {
+	case templateFilePath == context.StdinFilePath || templateFilePath == "":
+		// a template read from Stdin has no directory, let's use the working directory.
+		dir = "."
+	case !isDirectory(templateFilePath):
+		dir = filepath.Dir(templateFilePath)
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	return &orbitFS{dir: dir}
+}
+
+/*
+SetSandbox prevents the file system functions of the data-driven templates
+from reading outside of the given directory. An empty directory removes the restriction.
+*/
+func (g *OrbitGenerator) SetSandbox(root string) error {
+	if root == "" {
+		g.fs.root = ""
+		return nil
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to resolve the sandbox %s. Details:\n%s", root, err)
+	}
+
+	// the files are compared with their real path, so that a symbolic link does not escape the sandbox.
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to resolve the sandbox %s. Details:\n%s", root, err)
+	}
+
+	g.fs.root = real
+
+	return nil
+}
+
+// abs returns the absolute path of the given path, resolved relative to the directory of the data-driven template.
+func (fs *orbitFS) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(fs.dir, path)
+}
+
+/*
+resolve returns the absolute path of the given path, or an error if the path
+is outside of the sandbox. The symbolic links of an existing path are followed.
+*/
+func (fs *orbitFS) resolve(path string) (string, error) {
+	abs := fs.abs(path)
+	if fs.root == "" {
+		return abs, nil
+	}
+
+	real, err := realPath(abs)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to resolve the path %s. Details:\n%s", path, err)
+	}
+
+	if !isWithin(fs.root, real) {
+		return "", OrbitError.NewOrbitErrorf("path %s is outside of the sandbox %s", path, fs.root)
+	}
+
+	return abs, nil
+}
+
+/*
+readFile returns the content of the given file.
+
+This function is available in a data-driven template by using "readFile":
+
+	{{ readFile "certs/ca.pem" | nindent 4 }}
+*/
+func (fs *orbitFS) readFile(path string) (string, error) {
+	abs, err := fs.resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", path, err)
+	}
+
+	return string(data), nil
+}
+
+/*
+readLines returns the lines of the given file, without their line breaks.
+
+This function is available in a data-driven template by using "readLines".
+*/
+func (fs *orbitFS) readLines(path string) ([]string, error) {
+	data, err := fs.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == "" {
+		return []string{}, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	for index, line := range lines {
+		lines[index] = strings.TrimSuffix(line, "\r")
+	}
+
+	return lines, nil
+}
+
+/*
+glob returns the sorted paths of the files matching the given pattern (see filepath.Match).
+The paths are relative to the directory of the data-driven template if the pattern is relative,
+so that they may be given to the other file system functions.
+
+This function is available in a data-driven template by using "glob":
+
+	{{ range glob "migrations/*.sql" }}{{ . }}{{ end }}
+*/
+func (fs *orbitFS) glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(fs.abs(pattern))
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("pattern %s is not valid. Details:\n%s", pattern, err)
+	}
+
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if _, err := fs.resolve(match); err != nil {
+			return nil, err
+		}
+
+		if !filepath.IsAbs(pattern) {
+			if match, err = filepath.Rel(fs.dir, match); err != nil {
+				return nil, OrbitError.NewOrbitErrorf("unable to match the pattern %s. Details:\n%s", pattern, err)
+			}
+		}
+
+		paths = append(paths, match)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+/*
+fileExists returns true if the given file or directory exists.
+
+This function is available in a data-driven template by using "fileExists".
+*/
+func (fs *orbitFS) fileExists(path string) (bool, error) {
+	abs, err := fs.resolve(path)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(abs); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", path, err)
+	}
+
+	return true, nil
+}
+
+/*
+isDir returns true if the given path is an existing directory.
+
+This function is available in a data-driven template by using "isDir".
+*/
+func (fs *orbitFS) isDir(path string) (bool, error) {
+	abs, err := fs.resolve(path)
+	if err != nil {
+		return false, err
+	}
+
+	return isDirectory(abs), nil
+}
+
+/*
+relPath returns the given path relative to the given base path.
+
+This function is available in a data-driven template by using "relPath":
+
+	{{ relPath "config" "certs/ca.pem" }}
+*/
+func (fs *orbitFS) relPath(base string, path string) (string, error) {
+	rel, err := filepath.Rel(fs.abs(base), fs.abs(path))
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to make the path %s relative to %s. Details:\n%s", path, base, err)
+	}
+
+	return rel, nil
+}
+
+/*
+absPath returns the absolute path of the given path.
+
+This function is available in a data-driven template by using "absPath".
+*/
+func (fs *orbitFS) absPath(path string) string {
+	return fs.abs(path)
+}
+
+/*
+sha256File returns the hexadecimal SHA-256 checksum of the given file.
+
+This function is available in a data-driven template by using "sha256File".
+*/
+func (fs *orbitFS) sha256File(path string) (string, error) {
+	abs, err := fs.resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(abs)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", path, err)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to read the file %s. Details:\n%s", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// realPath returns the given absolute path with its symbolic links followed, as far as it exists.
+func realPath(abs string) (string, error) {
+	real, err := filepath.EvalSymlinks(abs)
+	if err == nil || !os.IsNotExist(err) {
+		return real, err
+	}
+
+	parent := filepath.Dir(abs)
+	if parent == abs {
+		return abs, nil
+	}
+
+	if real, err = realPath(parent); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(real, filepath.Base(abs)), nil
+}
+
+// isWithin returns true if the given path is the given directory or one of its descendants.
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// isDirectory returns true if the given path is an existing directory.
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
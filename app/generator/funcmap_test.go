@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"reflect"
 	"runtime"
 	"testing"
 )
@@ -32,3 +33,63 @@ func TestRun(t *testing.T) {
 		t.Error("String returned by run function is malformated!")
 	}
 }
+
+// Tests if the functions parsing and emitting structured data convert
+// a document from one format to another.
+func TestFormatFunctions(t *testing.T) {
+	// case 1: converts a YAML document into a .env document.
+	decoded, err := fromYaml("name: billing\nport: 8080\ndebug: false\nempty:\n")
+	if err != nil {
+		t.Errorf("YAML document should have been decoded, got %s!", err)
+	}
+
+	env, err := toDotenv(decoded.(map[string]interface{}))
+	if expected := "debug=\"false\"\nempty=\"\"\nname=\"billing\"\nport=\"8080\""; err != nil || env != expected {
+		t.Errorf("Result should have been %q, got %q (%v)!", expected, env, err)
+	}
+
+	// case 2: converts a .env document into a YAML document.
+	decodedEnv, err := fromDotenv("NAME=billing\nMOTD=\"hello\\nworld\"\n")
+	if err != nil {
+		t.Errorf(".env document should have been decoded, got %s!", err)
+	}
+
+	yml, err := toYaml(decodedEnv)
+	if expected := "MOTD: |-\n  hello\n  world\nNAME: billing"; err != nil || yml != expected {
+		t.Errorf("Result should have been %q, got %q (%v)!", expected, yml, err)
+	}
+
+	// case 3: converts a JSON document into a TOML document and back.
+	decoded, err = fromJson(`{"server": {"host": "localhost", "ports": [80, 443]}}`)
+	if err != nil {
+		t.Errorf("JSON document should have been decoded, got %s!", err)
+	}
+
+	tml, err := toToml(decoded)
+	if err != nil {
+		t.Errorf("TOML document should have been encoded, got %s!", err)
+	}
+
+	expected := map[string]interface{}{"server": map[string]interface{}{"host": "localhost", "ports": []interface{}{80.0, 443.0}}}
+	if decodedToml, err := fromToml(tml); err != nil || !reflect.DeepEqual(decodedToml, expected) {
+		t.Errorf("TOML document %q should have been decoded as %v, got %v (%v)!", tml, expected, decodedToml, err)
+	}
+
+	// case 4: encodes a nested dictionary into a .env document.
+	if _, err := toDotenv(map[string]interface{}{"server": map[string]interface{}{"host": "localhost"}}); err == nil {
+		t.Error("Nested dictionary should not have been encoded into a .env document!")
+	}
+
+	// case 5: decodes broken documents.
+	if _, err := fromYaml("name: [billing"); err == nil {
+		t.Error("Broken YAML document should not have been decoded!")
+	}
+
+	if _, err := fromJson("{"); err == nil {
+		t.Error("Broken JSON document should not have been decoded!")
+	}
+
+	if _, err := fromToml("name = "); err == nil {
+		t.Error("Broken TOML document should not have been decoded!")
+	}
+}
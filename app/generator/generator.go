@@ -7,13 +7,17 @@ package generator
 
 import (
 	"bytes"
-	"fmt"
+	gocontext "context"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/gulien/orbit/app/context"
 	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
 	"github.com/gulien/orbit/app/logger"
 
 	"github.com/Masterminds/sprig"
@@ -25,8 +29,21 @@ type (
 		// context is an instance of OrbitContext.
 		context *context.OrbitContext
 
-		// funcMap contains sprig functions and custom os function.
+		// funcMap contains sprig functions and the custom functions.
 		funcMap template.FuncMap
+
+		// fs provides the file system functions of the data-driven templates.
+		fs *orbitFS
+
+		// mode is the mode of the output files, zero to keep the mode of an existing file.
+		mode os.FileMode
+
+		// outputFilePath is the path of the output file, empty if none.
+		outputFilePath string
+
+		// outputs array contains the files generated by the output function
+		// during the last execution.
+		outputs []*OrbitOutput
 	}
 
 	// orbitData is a simple handler of the payload given by the user.
@@ -36,6 +53,16 @@ type (
 		// in a data-driven template.
 		Orbit map[string]interface{}
 	}
+
+	// orbitContextWriter is a writer which fails once its context is done,
+	// aborting the execution of a data-driven template.
+	orbitContextWriter struct {
+		// ctx is the context which allows to cancel the execution.
+		ctx gocontext.Context
+
+		// out is the underlying writer.
+		out io.Writer
+	}
 )
 
 // NewOrbitGenerator creates an instance of OrbitGenerator.
@@ -45,10 +72,28 @@ func NewOrbitGenerator(context *context.OrbitContext) *OrbitGenerator {
 	funcMap["verbose"] = isVerbose
 	funcMap["debug"] = isDebug
 	funcMap["run"] = run
+	funcMap["fromYaml"] = fromYaml
+	funcMap["toYaml"] = toYaml
+	funcMap["fromJson"] = fromJson
+	funcMap["fromToml"] = fromToml
+	funcMap["toToml"] = toToml
+	funcMap["fromDotenv"] = fromDotenv
+	funcMap["toDotenv"] = toDotenv
+
+	fs := newOrbitFS(context.TemplateFilePath)
+	funcMap["readFile"] = fs.readFile
+	funcMap["readLines"] = fs.readLines
+	funcMap["glob"] = fs.glob
+	funcMap["fileExists"] = fs.fileExists
+	funcMap["isDir"] = fs.isDir
+	funcMap["relPath"] = fs.relPath
+	funcMap["absPath"] = fs.absPath
+	funcMap["sha256File"] = fs.sha256File
 
 	g := &OrbitGenerator{
 		context: context,
 		funcMap: funcMap,
+		fs:      fs,
 	}
 
 	logger.Debugf("generator has been instantiated with context %s", g.context)
@@ -59,28 +104,50 @@ func NewOrbitGenerator(context *context.OrbitContext) *OrbitGenerator {
 /*
 Execute executes a data-driven template by applying it the data structure provided by the application context.
 
-Returns the resulting bytes.
+The files generated by the output function are available with Outputs.
+
+Returns the resulting bytes or an error once the given context is done.
 */
-func (g *OrbitGenerator) Execute() (bytes.Buffer, error) {
+func (g *OrbitGenerator) Execute(ctx gocontext.Context) (bytes.Buffer, error) {
 	var (
 		files []string
 		data  bytes.Buffer
 	)
 
-	files = append(files, g.context.TemplateFilePath)
-	files = append(files, g.context.Templates...)
-	tmpl, err := template.New(filepath.Base(g.context.TemplateFilePath)).Delims(g.context.TemplateDelimiters[0], g.context.TemplateDelimiters[1]).Funcs(g.funcMap).ParseFiles(files...)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return data, OrbitError.NewOrbitErrorf("unable to parse the template file %s. Details:\n%s", g.context.TemplateFilePath, err)
 	}
 
+	// the output function executes the named templates of the data-driven template.
+	var tmpl *template.Template
+	g.outputs = nil
+	tmpl = g.newTemplate(ctx, filepath.Base(g.context.TemplateFilePath)).Funcs(template.FuncMap{
+		"output": g.output(ctx, func() *template.Template { return tmpl }),
+	})
+
+	// the data-driven template may have been read from Stdin.
+	if g.context.TemplateContent != nil {
+		if _, err := tmpl.Parse(string(g.context.TemplateContent)); err != nil {
+			return data, OrbitError.NewOrbitErrorf("unable to parse the template file %s. Details:\n%s", g.context.TemplateFilePath, err)
+		}
+	} else {
+		files = append(files, g.context.TemplateFilePath)
+	}
+
+	files = append(files, g.context.Templates...)
+	if len(files) > 0 {
+		if _, err := tmpl.ParseFiles(files...); err != nil {
+			return data, OrbitError.NewOrbitErrorf("unable to parse the template file %s. Details:\n%s", g.context.TemplateFilePath, err)
+		}
+	}
+
 	tmpl.Option("missingkey=error")
 
 	orbitData := &orbitData{
-		Orbit: g.context.Payload,
+		Orbit: g.payload(),
 	}
 
-	if err := tmpl.Execute(&data, orbitData); err != nil {
+	if err := tmpl.Execute(&orbitContextWriter{ctx: ctx, out: &data}, orbitData); err != nil {
 		return data, OrbitError.NewOrbitErrorf("unable to execute the template file %s. Details:\n%s", g.context.TemplateFilePath, err)
 	}
 
@@ -89,6 +156,76 @@ func (g *OrbitGenerator) Execute() (bytes.Buffer, error) {
 	return data, nil
 }
 
+// newTemplate creates a template with the delimiters from the application context and the custom functions.
+func (g *OrbitGenerator) newTemplate(ctx gocontext.Context, name string) *template.Template {
+	var tmpl *template.Template
+	tmpl = template.New(name).Delims(g.context.TemplateDelimiters[0], g.context.TemplateDelimiters[1]).Funcs(g.funcMap).Funcs(template.FuncMap{
+		"include": include(ctx, func() *template.Template { return tmpl }),
+	})
+
+	return tmpl
+}
+
+// payload returns the payload from the application context, overridden by the values given by the user.
+func (g *OrbitGenerator) payload() map[string]interface{} {
+	if len(g.context.Overrides) > 0 {
+		return context.MergeData(g.context.Payload, g.context.Overrides)
+	}
+
+	return g.context.Payload
+}
+
+/*
+ExecuteText executes a data-driven template given as a string by applying it the given payload
+instead of the one from the application context. The additional templates are still available.
+
+Returns the resulting string.
+*/
+func (g *OrbitGenerator) ExecuteText(ctx gocontext.Context, name string, text string, payload map[string]interface{}) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to parse the template %s. Details:\n%s", name, err)
+	}
+
+	leftDelimiter := g.context.TemplateDelimiters[0]
+	if leftDelimiter == "" {
+		leftDelimiter = "{{"
+	}
+
+	// nothing to execute.
+	if !strings.Contains(text, leftDelimiter) {
+		return text, nil
+	}
+
+	tmpl, err := g.newTemplate(ctx, name).Parse(text)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to parse the template %s. Details:\n%s", name, err)
+	}
+
+	if len(g.context.Templates) > 0 {
+		if tmpl, err = tmpl.ParseFiles(g.context.Templates...); err != nil {
+			return "", OrbitError.NewOrbitErrorf("unable to parse the additional templates of %s. Details:\n%s", name, err)
+		}
+	}
+
+	tmpl.Option("missingkey=error")
+
+	var data bytes.Buffer
+	if err := tmpl.Execute(&orbitContextWriter{ctx: ctx, out: &data}, &orbitData{Orbit: payload}); err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to execute the template %s. Details:\n%s", name, err)
+	}
+
+	return data.String(), nil
+}
+
+// Write writes to the underlying writer if the context is not done.
+func (w *orbitContextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return w.out.Write(p)
+}
+
 /*
 Flush writes bytes into a file or to Stdout if no output path given.
 
@@ -96,39 +233,80 @@ This function should be called after Execute function.
 */
 func (g *OrbitGenerator) Flush(outputPath string, data bytes.Buffer) error {
 	if outputPath != "" {
-		return flushToFile(outputPath, data)
+		_, err := flushToFile(outputPath, data, g.mode)
+		return err
 	}
 
 	// ok, no output file given, let's flush the result to Stdout.
-	flushToStdout(data)
-	return nil
+	return flushToStdout(data)
+}
+
+// SetFileMode sets the mode of the output files. A zero mode keeps the mode of an existing file.
+func (g *OrbitGenerator) SetFileMode(mode os.FileMode) {
+	g.mode = mode
 }
 
 /*
-flushToFile writes bytes into a file.
+flushToFile writes bytes into a file, unless the file already contains these bytes.
+
+The bytes are written into a temporary file of the same directory which is then
+renamed, so that the file is never partially written. If the file does not exist,
+this function will create it and its parent directories.
 
-If the file does not exist, this function will create it.
+The file gets the given mode, or keeps its current mode if zero. A new file then gets
+the mode 0666 minus the umask, like a file created by a shell redirection. The owner
+and the group of an existing file are not kept: the file belongs to the current user.
+
+Returns true if the file has been written.
 */
-func flushToFile(outputPath string, data bytes.Buffer) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return OrbitError.NewOrbitErrorf("unable to create the output file %s. Details:\n%s", outputPath, err)
+func flushToFile(outputPath string, data bytes.Buffer, mode os.FileMode) (bool, error) {
+	// a symbolic link is kept, its target is written instead.
+	if target, err := filepath.EvalSymlinks(outputPath); err == nil {
+		outputPath = target
 	}
 
-	defer file.Close()
+	info, err := os.Stat(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, OrbitError.NewOrbitErrorf("unable to read the output file %s. Details:\n%s", outputPath, err)
+	}
 
-	_, err = file.Write(data.Bytes())
-	if err != nil {
-		return OrbitError.NewOrbitErrorf("unable to flushToFile into the output file %s. Details:\n%s", outputPath, err)
+	if mode == 0 && info != nil {
+		mode = info.Mode().Perm()
+	}
+
+	if info != nil {
+		current, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			return false, OrbitError.NewOrbitErrorf("unable to read the output file %s. Details:\n%s", outputPath, err)
+		}
+
+		if bytes.Equal(current, data.Bytes()) {
+			if mode != 0 && info.Mode().Perm() != mode {
+				if err := os.Chmod(outputPath, mode); err != nil {
+					return false, OrbitError.NewOrbitErrorf("unable to change the mode of the output file %s. Details:\n%s", outputPath, err)
+				}
+			}
+
+			logger.Infof("output file %s is up to date", outputPath)
+			return false, nil
+		}
+	}
+
+	if err := helpers.WriteFileAtomic(outputPath, data.Bytes(), mode); err != nil {
+		return false, OrbitError.NewOrbitErrorf("unable to write the output file %s. Details:\n%s", outputPath, err)
 	}
 
 	logger.Infof("output file %s has been created", outputPath)
 
-	return nil
+	return true, nil
 }
 
-// flushToStdout writes bytes to Stdout.
-func flushToStdout(data bytes.Buffer) {
+// flushToStdout writes bytes to Stdout, as is.
+func flushToStdout(data bytes.Buffer) error {
 	logger.Infof("no output file given, printing the result to Stdout")
-	fmt.Println(string(data.Bytes()))
+	if _, err := os.Stdout.Write(data.Bytes()); err != nil {
+		return OrbitError.NewOrbitErrorf("unable to write the result to Stdout. Details:\n%s", err)
+	}
+
+	return nil
 }
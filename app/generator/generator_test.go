@@ -1,10 +1,14 @@
 package generator
 
 import (
+	"bytes"
+	gocontext "context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/gulien/orbit/app/context"
@@ -19,59 +23,117 @@ func TestExecute(t *testing.T) {
 
 	// case 1: uses a broken data-driven template.
 	brokenTemplateFilePath, _ := filepath.Abs("../../_tests/broken-template.yml")
-	ctx, _ := context.NewOrbitContext(brokenTemplateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), brokenTemplateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g := NewOrbitGenerator(ctx)
-	if _, err := g.Execute(); err == nil {
+	if _, err := g.Execute(gocontext.Background()); err == nil {
 		t.Errorf("OrbitGenerator should not have been able to parse the data-driven template %s", brokenTemplateFilePath)
 	}
 
 	// case 2: uses a correct data-driven template.
 	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
-	ctx, _ = context.NewOrbitContext(templateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g = NewOrbitGenerator(ctx)
-	if _, err := g.Execute(); err != nil {
+	if _, err := g.Execute(gocontext.Background()); err != nil {
 		t.Errorf("OrbitGenerator should have been able to parse the data-driven template %s", templateFilePath)
 	}
 
 	// case 3: uses a broken data-driven template with a missing variable.
 	brokenTemplateFilePath, _ = filepath.Abs("../../_tests/broken-template-missing-var.yml")
-	ctx, _ = context.NewOrbitContext(brokenTemplateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), brokenTemplateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g = NewOrbitGenerator(ctx)
-	if _, err := g.Execute(); err == nil {
+	if _, err := g.Execute(gocontext.Background()); err == nil {
 		t.Errorf("OrbitGenerator should not have been able to render the data-driven template %s", brokenTemplateFilePath)
 	}
 
 	// case 4: uses a data-driven template with a missing additional template.
 	templateWithAdditionalTemplatesFilePath, _ := filepath.Abs("../../_tests/template-with-additional-templates.txt")
-	ctx, _ = context.NewOrbitContext(templateWithAdditionalTemplatesFilePath, "", "../../_tests/template-spacex.txt", nil)
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateWithAdditionalTemplatesFilePath, "", "../../_tests/template-spacex.txt", nil)
 	g = NewOrbitGenerator(ctx)
-	if _, err := g.Execute(); err == nil {
+	if _, err := g.Execute(gocontext.Background()); err == nil {
 		t.Errorf("OrbitGenerator should not have been able to render the data-driven template %s", templateWithAdditionalTemplatesFilePath)
 	}
 
 	// case 4: uses a data-driven template with all additional templates.
-	ctx, _ = context.NewOrbitContext(templateWithAdditionalTemplatesFilePath, "", "../../_tests/template-spacex.txt,../../_tests/template-blue-origin.txt", nil)
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateWithAdditionalTemplatesFilePath, "", "../../_tests/template-spacex.txt,../../_tests/template-blue-origin.txt", nil)
 	g = NewOrbitGenerator(ctx)
-	if _, err := g.Execute(); err != nil {
+	if _, err := g.Execute(gocontext.Background()); err != nil {
 		t.Errorf("OrbitGenerator should have been able to render the data-driven template %s", templateWithAdditionalTemplatesFilePath)
 	}
+
+	// case 5: uses a cancelled context.
+	cancelled, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err := g.Execute(cancelled); err == nil {
+		t.Errorf("OrbitGenerator should not have rendered the data-driven template %s with a cancelled context", templateWithAdditionalTemplatesFilePath)
+	}
+
+	// case 6: uses a template read from Stdin.
+	ctx.TemplateFilePath = context.StdinFilePath
+	ctx.TemplateContent = []byte(`{{ template "template-spacex.txt" . }}`)
+	if data, err := NewOrbitGenerator(ctx).Execute(gocontext.Background()); err != nil || data.Len() == 0 {
+		t.Error("OrbitGenerator should have been able to render the data-driven template read from Stdin!")
+	}
+
+	// case 7: uses a context cancelled while rendering.
+	w := &orbitContextWriter{ctx: cancelled, out: ioutil.Discard}
+	if _, err := w.Write([]byte("Falcon 9")); err == nil {
+		t.Error("orbitContextWriter should not have written anything with a cancelled context!")
+	}
+}
+
+// Tests if executing a data-driven template given as a string applies the given payload
+// and the additional templates.
+func TestExecuteText(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/template-with-additional-templates.txt")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "../../_tests/template-spacex.txt", nil)
+	g := NewOrbitGenerator(ctx)
+
+	// case 1: uses a string without template.
+	if result, err := g.ExecuteText(gocontext.Background(), "text", "echo Falcon 9", nil); err != nil || result != "echo Falcon 9" {
+		t.Error("String without template should have been returned as is!")
+	}
+
+	// case 2: uses a missing variable.
+	if _, err := g.ExecuteText(gocontext.Background(), "text", "echo {{ .Orbit.launcher }}", nil); err == nil {
+		t.Error("String with a missing variable should not have been rendered!")
+	}
+
+	// case 3: uses the given payload.
+	payload := map[string]interface{}{"launcher": "Falcon 9"}
+	if result, err := g.ExecuteText(gocontext.Background(), "text", "echo {{ .Orbit.launcher }}", payload); err != nil || result != "echo Falcon 9" {
+		t.Errorf("String should have been rendered with the given payload, got %q!", result)
+	}
+
+	// case 4: uses an additional template.
+	if _, err := g.ExecuteText(gocontext.Background(), "text", `{{ template "template-spacex.txt" . }}`, payload); err != nil {
+		t.Error("String should have been rendered with the additional templates!")
+	}
+
+	// case 5: uses values given by the user over the payload.
+	templateFilePath, _ = filepath.Abs("../../_tests/template-raw.yml")
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "SPACEX_LAUNCHERS,Falcon 9;BLUE_ORIGIN_LAUNCHERS,New Shepard;ESA_LAUNCHERS,Ariane 5", "", nil)
+	ctx.Overrides = map[string]interface{}{"ESA_LAUNCHERS": "Vega"}
+	data, err := NewOrbitGenerator(ctx).Execute(gocontext.Background())
+	if err != nil || !strings.Contains(data.String(), "Vega") || strings.Contains(data.String(), "Ariane 5") {
+		t.Errorf("Template should have been rendered with the values given by the user, got %q!", data.String())
+	}
 }
 
 // Tests if flushing from raw data source works as expected.
 func TestFlushFromRawDataSource(t *testing.T) {
 	templateFilePath, _ := filepath.Abs("../../_tests/template-raw.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "SPACEX_LAUNCHERS,Falcon 9, Falcon Heavy;BLUE_ORIGIN_LAUNCHERS,New Shepard, New Glenn;ESA_LAUNCHERS,Ariane 5, Vega", "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "SPACEX_LAUNCHERS,Falcon 9, Falcon Heavy;BLUE_ORIGIN_LAUNCHERS,New Shepard, New Glenn;ESA_LAUNCHERS,Ariane 5, Vega", "", nil)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -101,18 +163,18 @@ func TestFlushFromRawDataSource(t *testing.T) {
 func TestFlushFromYAMLDataSource(t *testing.T) {
 	dataSourceFilePath, _ := filepath.Abs("../../_tests/data-source.yml")
 	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -142,18 +204,18 @@ func TestFlushFromYAMLDataSource(t *testing.T) {
 func TestFlushFromTOMLDataSource(t *testing.T) {
 	dataSourceFilePath, _ := filepath.Abs("../../_tests/data-source.toml")
 	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -183,18 +245,18 @@ func TestFlushFromTOMLDataSource(t *testing.T) {
 func TestFlushFromJSONDataSource(t *testing.T) {
 	dataSourceFilePath, _ := filepath.Abs("../../_tests/data-source.json")
 	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -224,18 +286,18 @@ func TestFlushFromJSONDataSource(t *testing.T) {
 func TestFlushFromEnvFileDataSource(t *testing.T) {
 	dataSourceFilePath, _ := filepath.Abs("../../_tests/.env")
 	templateFilePath, _ := filepath.Abs("../../_tests/template-env.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "Values,"+dataSourceFilePath, "", nil)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "Values,"+dataSourceFilePath, "", nil)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -265,18 +327,18 @@ func TestFlushFromEnvFileDataSource(t *testing.T) {
 func TestAlternativeDelimiter(t *testing.T) {
 	templateFilePath, _ := filepath.Abs("../../_tests/template-alternative-delimiters.yml")
 	templateDelimiters := []string{"<<", ">>"}
-	ctx, _ := context.NewOrbitContext(templateFilePath, "SPACEX_LAUNCHERS,Falcon 9, Falcon Heavy;BLUE_ORIGIN_LAUNCHERS,New Shepard, New Glenn;ESA_LAUNCHERS,Ariane 5, Vega", "", templateDelimiters)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "SPACEX_LAUNCHERS,Falcon 9, Falcon Heavy;BLUE_ORIGIN_LAUNCHERS,New Shepard, New Glenn;ESA_LAUNCHERS,Ariane 5, Vega", "", templateDelimiters)
 	g := NewOrbitGenerator(ctx)
-	data, _ := g.Execute()
+	data, _ := g.Execute(gocontext.Background())
 
 	// case 1: uses an empty output path.
 	if err := g.Flush("", data); err != nil {
 		t.Error("OrbitGenerator should have been able to flush to Stdout!")
 	}
 
-	// case 2: uses a broken output path.
-	if err := g.Flush("/.../...", data); err == nil {
-		t.Error("OrbitGenerator should not have been able to flush to result file /...!")
+	// case 2: uses a broken output path (its parent directory is a file).
+	if err := g.Flush("generator.go/result.yml", data); err == nil {
+		t.Error("OrbitGenerator should not have been able to flush to result file generator.go/result.yml!")
 	}
 
 	// case 3: uses a correct output path.
@@ -301,3 +363,388 @@ func TestAlternativeDelimiter(t *testing.T) {
 		t.Error("result.yml should be equal to expected-result-raw-env.yml!")
 	}
 }
+
+// Tests if scaffolding a template directory renders the names and the content
+// of its files, copies the raw files verbatim, skips the ignored files and preserves the modes.
+func TestScaffold(t *testing.T) {
+	outputDir, _ := ioutil.TempDir("", "orbit-scaffold")
+	defer os.RemoveAll(outputDir)
+
+	templateDirPath, _ := filepath.Abs("../../_tests/scaffold")
+
+	// case 1: uses a template file instead of a directory.
+	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	if _, err := NewOrbitGenerator(ctx).Scaffold(gocontext.Background(), outputDir, nil, nil); err == nil {
+		t.Error("OrbitGenerator should not have been able to scaffold a template file!")
+	}
+
+	// case 2: uses a missing variable.
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateDirPath, "", "", nil)
+	if _, err := NewOrbitGenerator(ctx).Scaffold(gocontext.Background(), outputDir, []string{"assets"}, []string{"*.tmp"}); err == nil {
+		t.Error("OrbitGenerator should not have been able to scaffold the template directory without payload!")
+	}
+
+	// case 3: uses a correct payload.
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateDirPath, "name,billing", "", nil)
+	ctx.Overrides = map[string]interface{}{"docs": ""}
+	generated, err := NewOrbitGenerator(ctx).Scaffold(gocontext.Background(), outputDir, []string{"assets"}, []string{"*.tmp"})
+	if err != nil {
+		t.Errorf("OrbitGenerator should have been able to scaffold the template directory, got %s!", err)
+	}
+
+	if len(generated) != 4 {
+		t.Errorf("OrbitGenerator should have generated 4 files, got %v!", generated)
+	}
+
+	expected := map[string]string{
+		"README.md":               "# billing\n\nGenerated by Orbit.\n",
+		"billing/billing.go":      "package billing\n",
+		"billing/deploy.sh":       "#!/bin/sh\necho \"deploying billing\"\n",
+		"billing/assets/logo.svg": "{{ this is not a template }}\n",
+	}
+
+	for name, content := range expected {
+		if data, _ := ioutil.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name))); string(data) != content {
+			t.Errorf("%s should have been %q, got %q!", name, content, string(data))
+		}
+	}
+
+	for _, name := range []string{"notes.tmp", "docs/index.md"} {
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("%s should not have been generated!", name)
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(outputDir, "billing", "deploy.sh")); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0755) {
+		t.Error("Mode of deploy.sh should have been preserved!")
+	}
+}
+
+// Tests if generating the files of a manifest shares the payload between its entries
+// and only writes the files whose content has changed.
+func TestManifest(t *testing.T) {
+	// the paths of the manifest are relative to its directory.
+	resultFilePath, _ := filepath.Abs("../../_tests/manifest-result.yml")
+	rawResultFilePath, _ := filepath.Abs("../../_tests/manifest-result-raw.yml")
+	defer os.Remove(resultFilePath)
+	defer os.Remove(rawResultFilePath)
+
+	// case 1: uses a file without generate section.
+	templateFilePath, _ := filepath.Abs("../../_tests/template.yml")
+	if _, err := NewOrbitManifest(templateFilePath); err == nil {
+		t.Error("OrbitManifest should not have been instantiated without generate section!")
+	}
+
+	manifestFilePath, _ := filepath.Abs("../../_tests/orbit-generate.yml")
+	m, err := NewOrbitManifest(manifestFilePath)
+	if err != nil {
+		t.Fatalf("OrbitManifest should have been instantiated, got %s!", err)
+	}
+
+	// case 2: uses a shared payload missing an entry of the raw template.
+	parent, _ := context.NewOrbitContext(gocontext.Background(), manifestFilePath, "", "", nil)
+	if _, err := m.Generate(gocontext.Background(), parent); err == nil {
+		t.Error("OrbitManifest should not have generated the files with a missing entry!")
+	}
+
+	// case 3: uses a complete shared payload (the first file has been generated by case 2).
+	parent, _ = context.NewOrbitContext(gocontext.Background(), manifestFilePath, "ESA_LAUNCHERS,Ariane 5", "", nil)
+	changed, err := m.Generate(gocontext.Background(), parent)
+	if err != nil || !reflect.DeepEqual(changed, []string{rawResultFilePath}) {
+		t.Errorf("OrbitManifest should have generated the missing file, got %v (%v)!", changed, err)
+	}
+
+	if data, _ := ioutil.ReadFile(rawResultFilePath); !strings.Contains(string(data), "Falcon 9") || !strings.Contains(string(data), "Ariane 5") {
+		t.Errorf("manifest-result-raw.yml should have been generated with the shared and its own payload, got %q!", string(data))
+	}
+
+	// case 4: uses unchanged files.
+	if changed, err := m.Generate(gocontext.Background(), parent); err != nil || len(changed) != 0 {
+		t.Errorf("OrbitManifest should not have changed any file, got %v!", changed)
+	}
+
+	// case 5: uses a modified file without writing it.
+	ioutil.WriteFile(resultFilePath, []byte("companies: []"), 0644)
+	if diff, outdated, err := m.Diff(gocontext.Background(), parent); err != nil || !reflect.DeepEqual(outdated, []string{resultFilePath}) || !strings.Contains(diff, "-companies: []") {
+		t.Errorf("OrbitManifest should have found manifest-result.yml outdated, got %v!", outdated)
+	}
+
+	// case 6: uses a modified file.
+	if changed, err := m.Generate(gocontext.Background(), parent); err != nil || !reflect.DeepEqual(changed, []string{resultFilePath}) {
+		t.Errorf("OrbitManifest should have changed manifest-result.yml, got %v!", changed)
+	}
+}
+
+// Tests if the unified diff between an output file and a rendered content is correct.
+func TestDiff(t *testing.T) {
+	outputFile, _ := ioutil.TempFile("", "orbit-diff")
+	outputFile.WriteString("Falcon 1\nFalcon 9\nFalcon Heavy\nDragon\nCrew Dragon\nStarliner\nStarship\nSuper Heavy\n")
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+
+	// case 1: uses the same content.
+	if diff, err := Diff(outputFile.Name(), []byte("Falcon 1\nFalcon 9\nFalcon Heavy\nDragon\nCrew Dragon\nStarliner\nStarship\nSuper Heavy\n")); err != nil || diff != "" {
+		t.Errorf("Diff should have been empty, got %q!", diff)
+	}
+
+	// case 2: uses a modified content.
+	diff, _ := Diff(outputFile.Name(), []byte("Falcon 9\nFalcon Heavy\nDragon\nCrew Dragon\nStarliner\nStarship\nSuper Heavy\nNew Glenn"))
+	expected := "--- " + outputFile.Name() + "\n+++ " + outputFile.Name() + "\n" +
+		"@@ -1,4 +1,3 @@\n-Falcon 1\n Falcon 9\n Falcon Heavy\n Dragon\n" +
+		"@@ -6,3 +5,4 @@\n Starliner\n Starship\n Super Heavy\n+New Glenn\n\\ No newline at end of file\n"
+	if diff != expected {
+		t.Errorf("Diff should have been %q, got %q!", expected, diff)
+	}
+
+	// case 3: uses a missing output file.
+	if diff, err := Diff(outputFile.Name()+".missing", []byte("Falcon 9\n")); err != nil || !strings.HasSuffix(diff, "@@ -0,0 +1,1 @@\n+Falcon 9\n") {
+		t.Errorf("Diff should have added the content, got %q!", diff)
+	}
+}
+
+// Tests if flushing to a file creates its parent directories, sets its mode
+// and does not write it if its content has not changed.
+func TestFlushToFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit-flush")
+	defer os.RemoveAll(dir)
+
+	var data bytes.Buffer
+	data.WriteString("Falcon 9")
+	outputPath := filepath.Join(dir, "launchers", "spacex.txt")
+
+	// case 1: uses a missing parent directory.
+	if written, err := flushToFile(outputPath, data, 0); err != nil || !written {
+		t.Error("Output file should have been written with its parent directory!")
+	}
+
+	// a new output file gets the same mode as a file created by a shell redirection, which depends on the umask.
+	ioutil.WriteFile(filepath.Join(dir, "umask"), nil, 0666)
+	expected, _ := os.Stat(filepath.Join(dir, "umask"))
+	if info, _ := os.Stat(outputPath); info.Mode().Perm() != expected.Mode().Perm() {
+		t.Errorf("Mode of a new output file should have been %s, got %s!", expected.Mode().Perm(), info.Mode().Perm())
+	}
+
+	// case 2: uses the same content.
+	if written, err := flushToFile(outputPath, data, 0); err != nil || written {
+		t.Error("Output file should not have been written with the same content!")
+	}
+
+	// case 3: uses another content and a given mode.
+	data.WriteString(", Falcon Heavy")
+	if written, err := flushToFile(outputPath, data, 0600); err != nil || !written {
+		t.Error("Output file should have been written with another content!")
+	}
+
+	if info, _ := os.Stat(outputPath); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Mode of the output file should have been 0600, got %s!", info.Mode().Perm())
+	}
+
+	// case 4: uses the same content and another mode.
+	if written, err := flushToFile(outputPath, data, 0640); err != nil || written {
+		t.Error("Output file should not have been written with the same content!")
+	}
+
+	if info, _ := os.Stat(outputPath); runtime.GOOS != "windows" && info.Mode().Perm() != 0640 {
+		t.Errorf("Mode of the output file should have been changed to 0640, got %s!", info.Mode().Perm())
+	}
+
+	os.Chmod(outputPath, 0600)
+
+	// case 5: uses an existing output file without mode.
+	data.WriteString(", Starship")
+	flushToFile(outputPath, data, 0)
+	if info, _ := os.Stat(outputPath); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Mode of the output file should have been kept, got %s!", info.Mode().Perm())
+	}
+
+	if content, _ := ioutil.ReadFile(outputPath); string(content) != data.String() {
+		t.Errorf("Output file should contain %q, got %q!", data.String(), string(content))
+	}
+
+	// case 6: checks that no temporary file remains.
+	if files, _ := ioutil.ReadDir(filepath.Dir(outputPath)); len(files) != 1 {
+		t.Errorf("Output directory should only contain the output file, got %d file(s)!", len(files))
+	}
+}
+
+// Tests if replacing a managed block only modifies the content of the block.
+func TestReplaceBlock(t *testing.T) {
+	// case 1: uses an invalid block name.
+	if _, err := ReplaceBlock("hosts.missing", "dev hosts", "", []byte("10.0.0.1 falcon.local\n")); err == nil {
+		t.Error("Block with an invalid name should not have been replaced!")
+	}
+
+	// case 2: uses a missing file.
+	if content, err := ReplaceBlock("hosts.missing", "dev", "", []byte("10.0.0.1 falcon.local")); err != nil || string(content) != "# BEGIN orbit:dev\n10.0.0.1 falcon.local\n# END orbit:dev\n" {
+		t.Errorf("Block should have been added to an empty content, got %q!", string(content))
+	}
+
+	// case 3: uses a content without the block, with CRLF line endings.
+	content := "127.0.0.1 localhost\r\n::1 localhost"
+	expected := "127.0.0.1 localhost\r\n::1 localhost\r\n# BEGIN orbit:dev\r\n10.0.0.1 falcon.local\r\n10.0.0.2 heavy.local\r\n# END orbit:dev\r\n"
+	if result, err := replaceBlock([]byte(content), "dev", "#", []byte("10.0.0.1 falcon.local\n10.0.0.2 heavy.local\n")); err != nil || string(result) != expected {
+		t.Errorf("Block should have been appended with CRLF line endings, got %q!", string(result))
+	}
+
+	// case 4: uses a content with the block.
+	content = "127.0.0.1 localhost\r\n// BEGIN orbit:dev\r\n10.0.0.1 falcon.local\n// END orbit:dev\r\n::1 localhost"
+	expected = "127.0.0.1 localhost\r\n// BEGIN orbit:dev\r\n10.0.0.2 heavy.local\r\n// END orbit:dev\r\n::1 localhost"
+	if result, err := replaceBlock([]byte(content), "dev", "//", []byte("10.0.0.2 heavy.local")); err != nil || string(result) != expected {
+		t.Errorf("Block should have been replaced, got %q!", string(result))
+	}
+
+	// case 5: uses a content with another block.
+	content = "# BEGIN orbit:prod\n10.0.0.1 falcon.local\n# END orbit:prod\n"
+	if result, _ := replaceBlock([]byte(content), "dev", "#", nil); string(result) != content+"# BEGIN orbit:dev\n# END orbit:dev\n" {
+		t.Errorf("Other block should have been kept, got %q!", string(result))
+	}
+
+	// case 6: uses unterminated or duplicated blocks.
+	for _, broken := range []string{
+		"# BEGIN orbit:dev\n10.0.0.1 falcon.local\n",
+		"# END orbit:dev\n# BEGIN orbit:dev\n",
+		"# BEGIN orbit:dev\n# END orbit:dev\n# BEGIN orbit:dev\n# END orbit:dev\n",
+	} {
+		if _, err := replaceBlock([]byte(broken), "dev", "#", nil); err == nil {
+			t.Errorf("Block should not have been replaced in %q!", broken)
+		}
+	}
+}
+
+// Tests if a data-driven template generates a file for each call of the output function.
+func TestOutputs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit-outputs")
+	defer os.RemoveAll(dir)
+
+	templateFilePath, _ := filepath.Abs("../../_tests/template-outputs.yml")
+	outputFilePath := filepath.Join(dir, "result.yml")
+
+	// case 1: uses the same output twice.
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "services,billing billing", "", nil)
+	g := NewOrbitGenerator(ctx)
+	g.SetOutputFile(outputFilePath)
+	if _, err := g.Execute(gocontext.Background()); err == nil {
+		t.Error("OrbitGenerator should not have generated the same output twice!")
+	}
+
+	// case 2: uses distinct outputs, relative to the output file.
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "services,billing shipping", "", nil)
+	g = NewOrbitGenerator(ctx)
+	g.SetOutputFile(outputFilePath)
+	data, err := g.Execute(gocontext.Background())
+	if err != nil || data.Len() != 0 || len(g.Outputs()) != 2 {
+		t.Errorf("OrbitGenerator should have generated two outputs and an empty result, got %d output(s) (%v)!", len(g.Outputs()), err)
+	}
+
+	if _, outdated, _ := DiffOutputs(g.Outputs()); len(outdated) != 2 {
+		t.Errorf("Outputs should not be up to date before being flushed, got %v!", outdated)
+	}
+
+	if err := g.FlushOutputs(); err != nil {
+		t.Error("OrbitGenerator should have been able to flush the outputs!")
+	}
+
+	expected := "apiVersion: v1\nkind: Service\nmetadata:\n  name: shipping\n"
+	if content, _ := ioutil.ReadFile(filepath.Join(dir, "services", "shipping.yml")); string(content) != expected {
+		t.Errorf("shipping.yml should have been %q, got %q!", expected, string(content))
+	}
+
+	if _, outdated, _ := DiffOutputs(g.Outputs()); len(outdated) != 0 {
+		t.Errorf("Outputs should be up to date once flushed, got %v!", outdated)
+	}
+
+	// case 3: uses paths outside of the directory of the output file, or the output file itself.
+	for _, path := range []string{"/etc/passwd", "../shipping.yml", "services/../../shipping.yml", "result.yml"} {
+		if _, err := g.resolveOutput(path); err == nil {
+			t.Errorf("Output %s should not have been generated!", path)
+		}
+	}
+
+	// case 4: uses a path outside of the sandbox.
+	g = NewOrbitGenerator(ctx)
+	g.SetOutputFile(outputFilePath)
+	g.SetSandbox(filepath.Dir(templateFilePath))
+	if _, err := g.Execute(gocontext.Background()); err == nil || !strings.Contains(err.Error(), "sandbox") {
+		t.Errorf("Outputs should not have been generated outside of the sandbox, got %v!", err)
+	}
+}
+
+// Tests if the include function renders a named template as a string
+// and stops the recursive includes.
+func TestInclude(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/template-include.yml")
+
+	// case 1: uses a named template which may be piped.
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "name,billing;team,payments", "", nil)
+	g := NewOrbitGenerator(ctx)
+	expected := "metadata:\n  labels:\n    app: billing\n    team: payments\n"
+	if data, err := g.Execute(gocontext.Background()); err != nil || data.String() != expected {
+		t.Errorf("Result should have been %q, got %q (%v)!", expected, data.String(), err)
+	}
+
+	// case 2: uses a missing variable in the named template.
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "name,billing", "", nil)
+	if _, err := NewOrbitGenerator(ctx).Execute(gocontext.Background()); err == nil || !strings.Contains(err.Error(), "unable to include the template labels") {
+		t.Errorf("Error should have named the included template, got %v!", err)
+	}
+
+	// case 3: uses a template which includes itself.
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	if _, err := NewOrbitGenerator(ctx).ExecuteText(gocontext.Background(), "text", `{{ include "loop" . }}`, nil); err == nil || strings.Count(err.Error(), "\n") > 5 {
+		t.Errorf("Recursive include should have been stopped with a short error, got %v!", err)
+	}
+}
+
+// Tests if the file system functions resolve the paths relative to the
+// data-driven template and do not read outside of the sandbox.
+func TestFileSystemFunctions(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/fs/template.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+
+	// case 1: uses the file system functions without sandbox.
+	g := NewOrbitGenerator(ctx)
+	expected := strings.Join([]string{
+		"ca: |",
+		"  -----BEGIN CERTIFICATE-----",
+		"  MIIB",
+		"  -----END CERTIFICATE-----",
+		"ca_lines: 3",
+		"ca_sha256: 8010bfcee1842b2a4ce8f67c3e9455f0188f7a15bf39ec61a26feb75cd785821",
+		"migrations:",
+		"  - " + filepath.Join("migrations", "001_users.sql"),
+		"  - " + filepath.Join("migrations", "002_orders.sql"),
+		"has_ca: true",
+		"has_key: false",
+		"certs_is_dir: true",
+		"relative: " + filepath.Join("..", "certs", "ca.pem"),
+		"",
+	}, "\n")
+
+	if data, err := g.Execute(gocontext.Background()); err != nil || data.String() != expected {
+		t.Errorf("Result should have been %q, got %q (%v)!", expected, data.String(), err)
+	}
+
+	// case 2: reads a file outside of the sandbox.
+	if err := g.SetSandbox(filepath.Dir(templateFilePath)); err != nil {
+		t.Errorf("Sandbox should have been set, got %s!", err)
+	}
+
+	if _, err := g.ExecuteText(gocontext.Background(), "text", `{{ readFile "../template.yml" }}`, nil); err == nil || !strings.Contains(err.Error(), "outside of the sandbox") {
+		t.Errorf("Reading outside of the sandbox should have failed, got %v!", err)
+	}
+
+	// case 3: reads a file inside of the sandbox.
+	if data, err := g.Execute(gocontext.Background()); err != nil || data.String() != expected {
+		t.Errorf("Result should have been %q, got %q (%v)!", expected, data.String(), err)
+	}
+
+	// case 4: uses a symbolic link escaping the sandbox.
+	link := filepath.Join(filepath.Dir(templateFilePath), "escape.yml")
+	if err := os.Symlink("../template.yml", link); err == nil {
+		defer os.Remove(link)
+		if _, err := g.ExecuteText(gocontext.Background(), "text", `{{ readFile "escape.yml" }}`, nil); err == nil {
+			t.Error("Reading a symbolic link outside of the sandbox should have failed!")
+		}
+	}
+}
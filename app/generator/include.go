@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"bytes"
+	gocontext "context"
+	"text/template"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+// maxIncludeDepth is the maximum number of nested calls of the include function.
+const maxIncludeDepth = 100
+
+/*
+include returns the include function, which executes a named template
+(e.g. from the additional templates) with the given data and returns
+the result as a string, so that it may be piped:
+
+	{{ include "labels" . | nindent 4 }}
+
+This function is available in a data-driven template by using "include".
+*/
+func include(ctx gocontext.Context, tmpl func() *template.Template) func(name string, data interface{}) (string, error) {
+	var (
+		depth int
+
+		// recursionErr is returned as is by the nested includes, so that it is not wrapped at each level.
+		recursionErr error
+	)
+
+	return func(name string, data interface{}) (string, error) {
+		if depth >= maxIncludeDepth {
+			recursionErr = OrbitError.NewOrbitErrorf("unable to include the template %s: more than %d nested includes, the templates may include each other recursively", name, maxIncludeDepth)
+			return "", recursionErr
+		}
+
+		depth++
+		defer func() { depth-- }()
+
+		var result bytes.Buffer
+		if err := tmpl().ExecuteTemplate(&orbitContextWriter{ctx: ctx, out: &result}, name, data); err != nil {
+			if recursionErr != nil {
+				return "", recursionErr
+			}
+
+			return "", OrbitError.NewOrbitErrorf("unable to include the template %s. Details:\n%s", name, err)
+		}
+
+		return result.String(), nil
+	}
+}
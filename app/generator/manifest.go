@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
+	"github.com/gulien/orbit/app/logger"
+
+	"gopkg.in/yaml.v2"
+)
+
+type (
+	// OrbitManifest lists the files to generate in a single invocation.
+	OrbitManifest struct {
+		// filePath is the path of the file containing the manifest.
+		filePath string
+
+		// mode is the mode of the output files, zero to keep the mode of existing files.
+		mode os.FileMode
+
+		// sandbox is the directory the data-driven templates may not read outside of, empty if none.
+		sandbox string
+
+		// Entries array contains the files to generate.
+		Entries []*OrbitManifestEntry `yaml:"generate"`
+	}
+
+	// OrbitManifestEntry represents a file to generate as defined in the manifest.
+	OrbitManifestEntry struct {
+		// Template is the path of the data-driven template.
+		Template string `yaml:"template"`
+
+		// Output is the path of the resulting file.
+		Output string `yaml:"output"`
+
+		// Payload contains the entries merged over the shared payload,
+		// in the same format as the payload flag.
+		Payload string `yaml:"payload,omitempty"`
+
+		// Templates contains the additional templates added to the shared ones,
+		// in the same format as the templates flag.
+		Templates string `yaml:"templates,omitempty"`
+
+		// Delimiters is the optional pair of template delimiters.
+		Delimiters []string `yaml:"delimiters,omitempty"`
+	}
+)
+
+// NewOrbitManifest creates an instance of OrbitManifest from the generate section of the given file.
+func NewOrbitManifest(filePath string) (*OrbitManifest, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the manifest %s. Details:\n%s", filePath, err)
+	}
+
+	m := &OrbitManifest{filePath: filePath}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, OrbitError.NewOrbitErrorf("manifest %s is not a valid YAML file. Details:\n%s", filePath, err)
+	}
+
+	if len(m.Entries) == 0 {
+		return nil, OrbitError.NewOrbitErrorf("manifest %s has no generate section", filePath)
+	}
+
+	for index, entry := range m.Entries {
+		if entry.Template == "" || entry.Output == "" {
+			return nil, OrbitError.NewOrbitErrorf("entry %d of manifest %s must have a template and an output", index+1, filePath)
+		}
+
+		// the paths of an entry are relative to the manifest, not to the working directory.
+		entry.Template = m.resolve(entry.Template)
+		entry.Output = m.resolve(entry.Output)
+		entry.Payload = m.resolvePayload(entry.Payload)
+		entry.Templates = m.resolveTemplates(entry.Templates)
+	}
+
+	logger.Debugf("manifest %s has been instantiated with %d entries", filePath, len(m.Entries))
+
+	return m, nil
+}
+
+// resolve returns the given path relative to the directory of the manifest, if not absolute.
+func (m *OrbitManifest) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(m.filePath), path)
+}
+
+/*
+resolvePayload resolves the files of the given payload (key,value;key,value),
+an entry whose value is not an existing file being kept as is.
+*/
+func (m *OrbitManifest) resolvePayload(payload string) string {
+	if payload == "" {
+		return payload
+	}
+
+	entries := strings.Split(payload, ";")
+	for index, entry := range entries {
+		parts := strings.SplitN(entry, ",", 2)
+		if len(parts) == 2 && !filepath.IsAbs(parts[1]) && helpers.FileExists(m.resolve(parts[1])) {
+			entries[index] = parts[0] + "," + m.resolve(parts[1])
+		}
+	}
+
+	return strings.Join(entries, ";")
+}
+
+// resolveTemplates resolves the files of the given templates (path,path,path).
+func (m *OrbitManifest) resolveTemplates(templates string) string {
+	if templates == "" {
+		return templates
+	}
+
+	paths := strings.Split(templates, ",")
+	for index, path := range paths {
+		paths[index] = m.resolve(path)
+	}
+
+	return strings.Join(paths, ",")
+}
+
+// SetFileMode sets the mode of the output files. A zero mode keeps the mode of existing files.
+func (m *OrbitManifest) SetFileMode(mode os.FileMode) {
+	m.mode = mode
+}
+
+// SetSandbox prevents the data-driven templates from reading outside of the given directory.
+func (m *OrbitManifest) SetSandbox(root string) {
+	m.sandbox = root
+}
+
+/*
+Generate generates the files of the manifest. The entries share the payload,
+the additional templates and the delimiters of the given context, which are
+only decoded once.
+
+Returns the paths of the files whose content has changed, including the files
+generated by the output function: the other files are left untouched.
+*/
+func (m *OrbitManifest) Generate(ctx gocontext.Context, parent *context.OrbitContext) ([]string, error) {
+	var changed []string
+
+	err := m.render(ctx, parent, func(entry *OrbitManifestEntry, data bytes.Buffer, outputs []*OrbitOutput) error {
+		outputs = append([]*OrbitOutput{{Path: entry.Output, Data: data}}, outputs...)
+		for _, output := range outputs {
+			written, err := flushToFile(output.Path, output.Data, m.mode)
+			if err != nil {
+				return err
+			}
+
+			if written {
+				changed = append(changed, output.Path)
+			}
+		}
+
+		return nil
+	})
+
+	return changed, err
+}
+
+/*
+Diff renders the files of the manifest without writing them.
+
+Returns the unified diff between the files and their rendered content,
+and the paths of the files which are not up to date.
+*/
+func (m *OrbitManifest) Diff(ctx gocontext.Context, parent *context.OrbitContext) (string, []string, error) {
+	var (
+		diffs    bytes.Buffer
+		outdated []string
+	)
+
+	err := m.render(ctx, parent, func(entry *OrbitManifestEntry, data bytes.Buffer, outputs []*OrbitOutput) error {
+		diff, paths, err := DiffOutputs(append([]*OrbitOutput{{Path: entry.Output, Data: data}}, outputs...))
+		diffs.WriteString(diff)
+		outdated = append(outdated, paths...)
+
+		return err
+	})
+
+	return diffs.String(), outdated, err
+}
+
+/*
+render executes the data-driven template of each entry, then calls the given function
+with the result and the files generated by the output function.
+*/
+func (m *OrbitManifest) render(ctx gocontext.Context, parent *context.OrbitContext, fn func(entry *OrbitManifestEntry, data bytes.Buffer, outputs []*OrbitOutput) error) error {
+	for _, entry := range m.Entries {
+		orbitContext, err := context.NewOrbitContextFromParent(ctx, parent, entry.Template, entry.Payload, entry.Templates, entry.Delimiters)
+		if err != nil {
+			return OrbitError.NewOrbitErrorf("unable to generate %s from manifest %s. Details:\n%s", entry.Output, m.filePath, err)
+		}
+
+		g := NewOrbitGenerator(orbitContext)
+		g.SetOutputFile(entry.Output)
+		if err := g.SetSandbox(m.sandbox); err != nil {
+			return err
+		}
+
+		data, err := g.Execute(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(entry, data, g.Outputs()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"bytes"
+	gocontext "context"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+// OrbitOutput is a file generated by the output function of a data-driven template.
+type OrbitOutput struct {
+	// Path is the path of the file.
+	Path string
+
+	// Data is the content of the file.
+	Data bytes.Buffer
+}
+
+/*
+SetOutputFile sets the path of the output file of the data-driven template:
+the paths given to the output function are relative to its directory.
+*/
+func (g *OrbitGenerator) SetOutputFile(outputPath string) {
+	g.outputFilePath = outputPath
+}
+
+/*
+output returns the output function, which executes a named template
+of the data-driven template with the given data and stores the result
+as a file to generate at the given path.
+
+The path must be relative and may not go up: it is resolved relative to the directory
+of the output file, or of the data-driven template if none.
+
+This function is available in a data-driven template by using "output":
+
+	{{ range .Orbit.services }}{{ output (printf "k8s/%s.yml" .name) "service" . }}{{ end }}
+
+It returns an empty string, so that it does not modify the resulting bytes of the data-driven template.
+*/
+func (g *OrbitGenerator) output(ctx gocontext.Context, tmpl func() *template.Template) func(path string, name string, data interface{}) (string, error) {
+	return func(path string, name string, data interface{}) (string, error) {
+		if path == "" {
+			return "", OrbitError.NewOrbitErrorf("no path given for the output of template %s", name)
+		}
+
+		outputPath, err := g.resolveOutput(path)
+		if err != nil {
+			return "", err
+		}
+
+		for _, output := range g.outputs {
+			if output.Path == outputPath {
+				return "", OrbitError.NewOrbitErrorf("output %s is generated more than once", path)
+			}
+		}
+
+		output := &OrbitOutput{Path: outputPath}
+		if err := tmpl().ExecuteTemplate(&orbitContextWriter{ctx: ctx, out: &output.Data}, name, data); err != nil {
+			return "", OrbitError.NewOrbitErrorf("unable to execute the template %s of output %s. Details:\n%s", name, path, err)
+		}
+
+		logger.Debugf("output %s has been rendered from template %s", path, name)
+		g.outputs = append(g.outputs, output)
+
+		return "", nil
+	}
+}
+
+// resolveOutput returns the path of the file generated by the output function for the given path.
+func (g *OrbitGenerator) resolveOutput(path string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(path))
+	switch {
+	case filepath.IsAbs(rel), filepath.VolumeName(rel) != "", strings.HasPrefix(rel, string(filepath.Separator)):
+		return "", OrbitError.NewOrbitErrorf("output %s must be a relative path", path)
+	case rel == "..", strings.HasPrefix(rel, ".."+string(filepath.Separator)):
+		return "", OrbitError.NewOrbitErrorf("output %s may not be outside of the directory of the output file", path)
+	case rel == ".":
+		return "", OrbitError.NewOrbitErrorf("output %s is not a file", path)
+	}
+
+	base := g.fs.dir
+	if g.outputFilePath != "" {
+		base = filepath.Dir(g.outputFilePath)
+	}
+
+	outputPath := filepath.Join(base, rel)
+	abs, err := filepath.Abs(outputPath)
+	if err != nil {
+		return "", OrbitError.NewOrbitErrorf("unable to resolve the output %s. Details:\n%s", path, err)
+	}
+
+	if g.outputFilePath != "" {
+		if outputFileAbs, err := filepath.Abs(g.outputFilePath); err == nil && outputFileAbs == abs {
+			return "", OrbitError.NewOrbitErrorf("output %s is the output file of the data-driven template", path)
+		}
+	}
+
+	// a symbolic link may not escape the sandbox either.
+	if _, err := g.fs.resolve(abs); err != nil {
+		return "", err
+	}
+
+	return outputPath, nil
+}
+
+// Outputs returns the files generated by the output function during the last execution.
+func (g *OrbitGenerator) Outputs() []*OrbitOutput {
+	return g.outputs
+}
+
+// FlushOutputs writes the files generated by the output function during the last execution.
+func (g *OrbitGenerator) FlushOutputs() error {
+	for _, output := range g.outputs {
+		if _, err := flushToFile(output.Path, output.Data, g.mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+/*
+Scaffold renders the template directory provided by the application context into the given output directory.
+
+The names of the files and directories are data-driven templates too: a file or a directory whose
+name is rendered as an empty string is skipped. The files matching a raw pattern are copied verbatim,
+the files and directories matching an ignore pattern are skipped. A pattern is matched against the
+slash-separated path of a file relative to the template directory and against its name, and applies
+to the content of a matching directory.
+
+The modes of the files and directories are preserved.
+
+Returns the paths of the generated files.
+*/
+func (g *OrbitGenerator) Scaffold(ctx gocontext.Context, outputDir string, raw []string, ignore []string) ([]string, error) {
+	root := g.context.TemplateFilePath
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the template directory %s. Details:\n%s", root, err)
+	}
+
+	if !info.IsDir() {
+		return nil, OrbitError.NewOrbitErrorf("the template %s is not a directory", root)
+	}
+
+	for _, pattern := range append(append([]string{}, raw...), ignore...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, OrbitError.NewOrbitErrorf("pattern %s is not valid. Details:\n%s", pattern, err)
+		}
+	}
+
+	var (
+		payload   = g.payload()
+		generated []string
+	)
+
+	err = filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return os.MkdirAll(outputDir, info.Mode().Perm())
+		}
+
+		rel = filepath.ToSlash(rel)
+		if matchAny(ignore, rel) {
+			logger.Debugf("%s matches an ignore pattern, skipping it", rel)
+			return skip(info)
+		}
+
+		target, err := g.renderPath(ctx, rel, payload)
+		if err != nil {
+			return err
+		}
+
+		if target == "" {
+			logger.Debugf("name of %s has been rendered as an empty string, skipping it", rel)
+			return skip(info)
+		}
+
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(target))
+		if info.IsDir() {
+			if err := os.MkdirAll(outputPath, info.Mode().Perm()); err != nil {
+				return err
+			}
+
+			return os.Chmod(outputPath, info.Mode().Perm())
+		}
+
+		if !info.Mode().IsRegular() {
+			logger.Infof("%s is not a regular file, skipping it", rel)
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		if !matchAny(raw, rel) {
+			rendered, err := g.ExecuteText(ctx, rel, string(content), payload)
+			if err != nil {
+				return err
+			}
+
+			content = []byte(rendered)
+		}
+
+		// the file gets the mode of the template file, and is written atomically.
+		if _, err := flushToFile(outputPath, *bytes.NewBuffer(content), info.Mode().Perm()); err != nil {
+			return err
+		}
+
+		generated = append(generated, outputPath)
+
+		return nil
+	})
+
+	if err != nil {
+		return generated, OrbitError.NewOrbitErrorf("unable to scaffold the template directory %s into %s. Details:\n%s", root, outputDir, err)
+	}
+
+	return generated, nil
+}
+
+// renderPath renders the given slash-separated relative path. Returns an empty path if one of its elements is empty.
+func (g *OrbitGenerator) renderPath(ctx gocontext.Context, rel string, payload map[string]interface{}) (string, error) {
+	rendered, err := g.ExecuteText(ctx, rel, rel, payload)
+	if err != nil {
+		return "", err
+	}
+
+	for _, element := range strings.Split(rendered, "/") {
+		if strings.TrimSpace(element) == "" {
+			return "", nil
+		}
+	}
+
+	rendered = path.Clean(rendered)
+	if rendered == ".." || strings.HasPrefix(rendered, "../") || path.IsAbs(rendered) {
+		return "", OrbitError.NewOrbitErrorf("name of %s has been rendered as %s, which is outside of the output directory", rel, rendered)
+	}
+
+	return rendered, nil
+}
+
+/*
+matchAny returns true if the given slash-separated path, one of its parent
+directories or one of their names matches one of the given patterns.
+*/
+func matchAny(patterns []string, rel string) bool {
+	elements := strings.Split(rel, "/")
+	for index, element := range elements {
+		prefix := strings.Join(elements[:index+1], "/")
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, prefix); ok {
+				return true
+			}
+
+			if ok, _ := path.Match(pattern, element); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// skip returns the error which skips the given file or directory when walking a directory.
+func skip(info os.FileInfo) error {
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"os"
+
+	"github.com/gulien/orbit/app/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// graphFormat is the format of the graph.
+	graphFormat string
+
+	// graphCmd is the instance of graph command.
+	graphCmd = &cobra.Command{
+		Use:           "graph",
+		Short:         "Prints the graph of the tasks defined in a configuration file",
+		Long:          "Prints the graph of the tasks defined in a configuration file, as an ASCII tree, a Graphviz DOT graph or a Mermaid flowchart.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          graph,
+	}
+)
+
+// init initializes a graphCmd instance with some flags and adds it to the RootCmd.
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", runner.TreeGraphFormat, "specify the format of the graph: tree, dot or mermaid")
+	RootCmd.AddCommand(graphCmd)
+}
+
+// graph prints the graph of the given tasks or of all the tasks if none given.
+func graph(cmd *cobra.Command, args []string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	r, err := newOrbitRunner(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.Graph(ctx, os.Stdout, graphFormat, args[:]...)
+}
@@ -1,10 +1,95 @@
 // Package helpers implements simple functions used across the application.
 package helpers
 
-import "os"
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// TempFilePrefix is the prefix of the temporary files written by CopyFileAtomic.
+const TempFilePrefix = ".orbit-tmp-"
 
 // FileExists returns true if the specified path exists.
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// WriteFileAtomic writes the given data into the given path like CopyFileAtomic.
+func WriteFileAtomic(path string, data []byte, mode os.FileMode) error {
+	return CopyFileAtomic(path, bytes.NewReader(data), mode, nil)
+}
+
+/*
+CopyFileAtomic copies the given reader into a temporary file of the same directory, then renames
+it to the given path unless the given function (if any), called once the content has been copied,
+returns an error. The directory is created if it does not exist.
+
+The file gets the given mode, or the mode 0666 minus the umask if zero.
+*/
+func CopyFileAtomic(path string, src io.Reader, mode os.FileMode, check func() error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := createTempFile(dir, TempFilePrefix+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+
+	// the mode is changed before writing, so that the data is never readable by others if the mode does not allow it.
+	if mode != 0 {
+		err = tmp.Chmod(mode)
+	}
+
+	if err == nil {
+		_, err = io.Copy(tmp, src)
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && check != nil {
+		err = check()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+/*
+createTempFile creates a new file in the given directory, named after the given prefix and a random suffix.
+
+Unlike ioutil.TempFile, which creates a file with the mode 0600, the file gets the mode 0666 minus the umask.
+*/
+func createTempFile(dir string, prefix string) (*os.File, error) {
+	for try := 0; try < 10000; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+
+		return file, err
+	}
+
+	return nil, fmt.Errorf("unable to create a temporary file in %s", dir)
+}
@@ -1,7 +1,12 @@
 package helpers
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -12,3 +17,41 @@ func TestFileExists(t *testing.T) {
 		t.Error("File should exist!")
 	}
 }
+
+// Tests WriteFileAtomic and CopyFileAtomic functions.
+func TestWriteFileAtomic(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "launchers", "falcon.txt")
+
+	// case 1: uses a directory which does not exist.
+	if err := WriteFileAtomic(path, []byte("Falcon 9"), 0600); err != nil {
+		t.Error("File should have been written!")
+	}
+
+	if data, _ := ioutil.ReadFile(path); string(data) != "Falcon 9" {
+		t.Errorf("File should contain the given data, got %q!", string(data))
+	}
+
+	if info, err := os.Stat(path); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != 0600) {
+		t.Error("File should have the given mode!")
+	}
+
+	// case 2: uses a check which fails.
+	err := CopyFileAtomic(path, strings.NewReader("Falcon Heavy"), 0600, func() error {
+		return errors.New("corrupted")
+	})
+
+	if err == nil {
+		t.Error("File should not have been written!")
+	}
+
+	if data, _ := ioutil.ReadFile(path); string(data) != "Falcon 9" {
+		t.Errorf("File should not have been replaced, got %q!", string(data))
+	}
+
+	if infos, _ := ioutil.ReadDir(filepath.Dir(path)); len(infos) != 1 {
+		t.Error("Temporary file should have been removed!")
+	}
+}
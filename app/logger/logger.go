@@ -4,7 +4,12 @@ Package logger implements a simple helper for displaying output to the user.
 package logger
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	OrbitError "github.com/gulien/orbit/app/error"
 
@@ -15,8 +20,25 @@ import (
 type orbitLogger struct {
 	// logger is an instance of logrus logger.
 	logger *logrus.Logger
+
+	// secrets contains the values which should never be displayed.
+	secrets []string
+
+	// masker replaces the secrets by a mask.
+	masker *strings.Replacer
+
+	// mu guards the secrets and the masker, which may be used by many goroutines.
+	mu sync.RWMutex
 }
 
+const (
+	// secretMask is displayed instead of a secret.
+	secretMask = "***"
+
+	// minSecretLineLength is the minimum length of a line of a multi-line secret to mask, so that short lines are not masked across all output.
+	minSecretLineLength = 4
+)
+
 // newOrbitLogged creates an instance of orbitLogger.
 func newOrbitLogger() *orbitLogger {
 	l := logrus.New()
@@ -36,28 +58,152 @@ func SetLevel(level logrus.Level) {
 	houston.logger.SetLevel(level)
 }
 
+// SetOutput updates the writer of the logs (Stdout by default).
+func SetOutput(w io.Writer) {
+	houston.logger.Out = w
+}
+
 // GetLevel returns the current level of messages which are logged.
 func GetLevel() logrus.Level {
 	return houston.logger.Level
 }
 
+/*
+AddSecrets registers values which should never be displayed.
+
+Once registered, a secret is replaced by a mask in every log. As the outputs
+are masked line by line, each line of a multi-line secret is registered too,
+unless it is too short.
+*/
+func AddSecrets(secrets ...string) {
+	houston.mu.Lock()
+	defer houston.mu.Unlock()
+
+	for _, secret := range secrets {
+		if secret != "" {
+			houston.secrets = append(houston.secrets, secret)
+		}
+
+		if !strings.Contains(secret, "\n") {
+			continue
+		}
+
+		for _, line := range strings.Split(secret, "\n") {
+			if line = strings.TrimSuffix(line, "\r"); len(strings.TrimSpace(line)) >= minSecretLineLength {
+				houston.secrets = append(houston.secrets, line)
+			}
+		}
+	}
+
+	// the longest secrets are replaced first, as a secret may contain another one.
+	sort.Slice(houston.secrets, func(i, j int) bool {
+		return len(houston.secrets[i]) > len(houston.secrets[j])
+	})
+
+	pairs := make([]string, 0, len(houston.secrets)*2)
+	for _, secret := range houston.secrets {
+		pairs = append(pairs, secret, secretMask)
+	}
+
+	houston.masker = strings.NewReplacer(pairs...)
+}
+
+// HasSecrets returns true if at least one secret has been registered.
+func HasSecrets() bool {
+	houston.mu.RLock()
+	defer houston.mu.RUnlock()
+
+	return houston.masker != nil
+}
+
+// Mask replaces the registered secrets from the given string by a mask.
+func Mask(s string) string {
+	houston.mu.RLock()
+	defer houston.mu.RUnlock()
+
+	if houston.masker == nil {
+		return s
+	}
+
+	return houston.masker.Replace(s)
+}
+
+/*
+MaskPartial replaces the registered secrets from the given incomplete line by a mask.
+
+Returns the masked beginning of the line, which may be displayed right away,
+and its end, which may be the beginning of a secret and should be kept until
+the line is complete.
+*/
+func MaskPartial(s string) (string, string) {
+	houston.mu.RLock()
+	defer houston.mu.RUnlock()
+
+	if houston.masker == nil {
+		return s, ""
+	}
+
+	// from is the offset the end of the line may start from.
+	from := 0
+	for {
+		held := len(s)
+		for _, secret := range houston.secrets {
+			for index := maxInt(from, len(s)-len(secret)+1); index < held; index++ {
+				if strings.HasPrefix(secret, s[index:]) {
+					held = index
+					break
+				}
+			}
+		}
+
+		// a complete secret must not be cut either.
+		end := held
+		for _, secret := range houston.secrets {
+			for index := maxInt(0, held-len(secret)+1); index < held; index++ {
+				if strings.HasPrefix(s[index:], secret) && index+len(secret) > end {
+					end = index + len(secret)
+				}
+			}
+		}
+
+		if end == held {
+			return houston.masker.Replace(s[:held]), s[held:]
+		}
+
+		from = end
+	}
+}
+
+// maxInt returns the largest of the given integers.
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
 // Infof logs information using the Houston logger.
 func Infof(message string, args ...interface{}) {
-	houston.logger.Infof(message, args...)
+	if houston.logger.Level >= logrus.InfoLevel {
+		houston.logger.Info(Mask(fmt.Sprintf(message, args...)))
+	}
 }
 
 // Debugf logs debug information using the Houston logger.
 func Debugf(message string, args ...interface{}) {
-	houston.logger.Debugf(message, args...)
+	if houston.logger.Level >= logrus.DebugLevel {
+		houston.logger.Debug(Mask(fmt.Sprintf(message, args...)))
+	}
 }
 
 // Error logs error information using the Houston logger.
 func Error(err error) {
 	if _, ok := err.(*OrbitError.OrbitError); ok {
-		houston.logger.Error(err.Error())
+		houston.logger.Error(Mask(err.Error()))
 	} else if GetLevel() == logrus.DebugLevel {
 		// errors which are not "OrbitError" are not relevant unless we are
 		// in debug mode.
-		houston.logger.Error(err.Error())
+		houston.logger.Error(Mask(err.Error()))
 	}
 }
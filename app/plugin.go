@@ -0,0 +1,168 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
+	"github.com/gulien/orbit/app/logger"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// pluginPrefix is the prefix of the plugins executables.
+	pluginPrefix = "orbit-"
+
+	// default directory of the project plugins.
+	pluginsDir = ".orbit/plugins"
+)
+
+/*
+Execute executes the command given by the user.
+
+If the command is not a built-in command, looks for an executable named
+orbit-<command> in the directory .orbit/plugins or in the PATH and runs it
+with the remaining arguments.
+*/
+func Execute() error {
+	if plugin, args := findPlugin(os.Args[1:]); plugin != "" {
+		RootCmd.PersistentPreRun(RootCmd, args)
+		return runPlugin(plugin, args)
+	}
+
+	return RootCmd.Execute()
+}
+
+// findPlugin returns the path of the plugin called by the given arguments and its arguments,
+// or an empty path if the arguments do not call a plugin.
+func findPlugin(args []string) (string, []string) {
+	// parses the global flags which may be given before the name of the plugin.
+	flags := pflag.NewFlagSet(RootCmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(RootCmd.PersistentFlags())
+	flags.SetInterspersed(false)
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return "", nil
+	}
+
+	name := flags.Arg(0)
+	if name == "help" || strings.ContainsAny(name, `/\`) {
+		return "", nil
+	}
+
+	for _, cmd := range RootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return "", nil
+		}
+	}
+
+	// the plugins of the project take precedence over the ones from the PATH.
+	for _, candidate := range []string{filepath.Join(pluginsDir, pluginPrefix+name), pluginPrefix + name} {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, flags.Args()[1:]
+		}
+	}
+
+	return "", nil
+}
+
+/*
+runPlugin runs the given plugin with the given arguments.
+
+The plugin receives the following environment variables:
+ORBIT_BIN (the path of the Orbit executable), ORBIT_CONFIG (the absolute path
+of the configuration file) and ORBIT_PAYLOAD (the path of a temporary JSON file
+containing the payload).
+*/
+func runPlugin(plugin string, args []string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	payloadData, err := context.RetrievePayload(ctx, payload, templates)
+	if err != nil {
+		return err
+	}
+
+	payloadFile, err := ioutil.TempFile("", "orbit-payload")
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to create the payload file of plugin %s. Details:\n%s", plugin, err)
+	}
+
+	defer os.Remove(payloadFile.Name())
+
+	err = json.NewEncoder(payloadFile).Encode(payloadData)
+	payloadFile.Close()
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to encode the payload of plugin %s. Details:\n%s", plugin, err)
+	}
+
+	configFilePath := templateFilePath
+	if configFilePath == "" && helpers.FileExists(orbitFilePath) {
+		configFilePath = orbitFilePath
+	}
+
+	if configFilePath != "" {
+		configFilePath, _ = filepath.Abs(configFilePath)
+	}
+
+	bin, _ := os.Executable()
+
+	e := exec.CommandContext(ctx, plugin, args...)
+	e.Stdout = os.Stdout
+	e.Stderr = os.Stderr
+	e.Stdin = os.Stdin
+	e.Env = append(os.Environ(),
+		"ORBIT_BIN="+bin,
+		"ORBIT_CONFIG="+configFilePath,
+		"ORBIT_PAYLOAD="+payloadFile.Name(),
+	)
+
+	logger.Infof("executing plugin %s", e.Args)
+
+	if err := e.Run(); err != nil {
+		// like the plugin, Orbit exits with its exit code.
+		if exitErr, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+			return &PluginExitError{plugin: plugin, err: exitErr}
+		}
+
+		return OrbitError.NewOrbitErrorf("plugin %s has failed. Details:\n%s", plugin, err)
+	}
+
+	return nil
+}
+
+/*
+PluginExitError is returned when a plugin exits with a non-zero code.
+
+As it is not an OrbitError, it is only logged in debug mode: the plugin prints its own errors.
+*/
+type PluginExitError struct {
+	// plugin is the path of the plugin.
+	plugin string
+
+	// err is the error returned by the plugin.
+	err *exec.ExitError
+}
+
+// Error is the implementation of the function Error from the error interface.
+func (e *PluginExitError) Error() string {
+	return fmt.Sprintf("plugin %s has failed. Details:\n%s", e.plugin, e.err)
+}
+
+// ExitCode returns the exit code of the plugin, or 1 if it has been killed by a signal.
+func (e *PluginExitError) ExitCode() int {
+	if status, ok := e.err.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+		return status.ExitStatus()
+	}
+
+	return 1
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests if a plugin is found in the PATH and run with the expected environment variables.
+func TestPlugin(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit-plugins")
+	defer os.RemoveAll(dir)
+
+	logFilePath := filepath.Join(dir, "plugin.log")
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$ORBIT_BIN\" \"$ORBIT_CONFIG\" \"$ORBIT_PAYLOAD\" > \"$ORBIT_PLUGIN_LOG\"\n" +
+		"cat \"$ORBIT_PAYLOAD\" >> \"$ORBIT_PLUGIN_LOG\"\n" +
+		"exit \"$1\"\n"
+	ioutil.WriteFile(filepath.Join(dir, "orbit-foo"), []byte(script), 0755)
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("ORBIT_PLUGIN_LOG", logFilePath)
+	defer os.Unsetenv("ORBIT_PLUGIN_LOG")
+
+	defer func() { payload, templateFilePath = "", "" }()
+
+	// case 1: uses a built-in command.
+	if plugin, _ := findPlugin([]string{"run", "foo"}); plugin != "" {
+		t.Errorf("Built-in command should not have been taken for a plugin, got %s!", plugin)
+	}
+
+	// case 2: uses a command which is neither a built-in command nor a plugin.
+	if plugin, _ := findPlugin([]string{"bar"}); plugin != "" {
+		t.Errorf("Missing plugin should not have been found, got %s!", plugin)
+	}
+
+	// case 3: uses a plugin after some global flags.
+	plugin, args := findPlugin([]string{"-f", "../_tests/orbit.yml", "-p", "launcher,Falcon 9", "foo", "3"})
+	if plugin != filepath.Join(dir, "orbit-foo") || len(args) != 1 || args[0] != "3" {
+		t.Fatalf("Plugin should have been found with its arguments, got %s %v!", plugin, args)
+	}
+
+	// case 4: uses a plugin which exits with a non-zero code.
+	err := runPlugin(plugin, args)
+	if exitErr, ok := err.(*PluginExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("Exit code of the plugin should have been propagated, got %v!", err)
+	}
+
+	data, _ := ioutil.ReadFile(logFilePath)
+	lines := strings.SplitN(string(data), "\n", 4)
+	if len(lines) != 4 {
+		t.Fatalf("Plugin should have received the environment variables, got %q!", string(data))
+	}
+
+	if lines[0] == "" {
+		t.Error("ORBIT_BIN should have been given to the plugin!")
+	}
+
+	if config, _ := filepath.Abs("../_tests/orbit.yml"); lines[1] != config {
+		t.Errorf("ORBIT_CONFIG should have been the absolute path of the configuration file, got %s!", lines[1])
+	}
+
+	if !strings.Contains(lines[3], `"launcher":"Falcon 9"`) {
+		t.Errorf("ORBIT_PAYLOAD should have contained the payload, got %q!", lines[3])
+	}
+
+	if _, err := os.Stat(lines[2]); !os.IsNotExist(err) {
+		t.Error("Payload file should have been removed once the plugin has exited!")
+	}
+
+	// case 5: uses a plugin which succeeds.
+	if err := runPlugin(plugin, []string{"0"}); err != nil {
+		t.Errorf("Plugin should have succeeded, got %v!", err)
+	}
+}
@@ -2,6 +2,12 @@
 package app
 
 import (
+	gocontext "context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/gulien/orbit/app/logger"
 
 	"github.com/sirupsen/logrus"
@@ -26,6 +32,9 @@ var (
 	// debug enables debug logs if true.
 	debug bool
 
+	// timeout is the maximum duration of a command, no limit if zero.
+	timeout time.Duration
+
 	// RootCmd is the instance of the root of all commands.
 	RootCmd = &cobra.Command{
 		Use:           "orbit",
@@ -45,9 +54,46 @@ var (
 )
 
 func init() {
-	RootCmd.PersistentFlags().StringVarP(&templateFilePath, "file", "f", "", "specify the path of a data-driven template")
+	RootCmd.PersistentFlags().StringVarP(&templateFilePath, "file", "f", "", "specify the path of a data-driven template, or - to read it from Stdin")
 	RootCmd.PersistentFlags().StringVarP(&payload, "payload", "p", "", "specify a map of YAML files, TOML files, JSON files, .env files and raw data")
 	RootCmd.PersistentFlags().StringVarP(&templates, "templates", "t", "", "specify a map of additional templates")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "set logging to info level")
 	RootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "set logging to debug level")
+	RootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "specify the maximum duration of the command (e.g. 5m), after which the running processes are killed")
+}
+
+/*
+newContext returns a context which is done once the timeout given by
+the user has elapsed or once Orbit receives an interrupt or a termination signal.
+
+A second signal is not caught, so that the user may always force Orbit to exit.
+*/
+func newContext() (gocontext.Context, gocontext.CancelFunc) {
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	if timeout > 0 {
+		// the timeout context is built on top of the cancelable one, so that both are released by the returned function.
+		var cancelTimeout gocontext.CancelFunc
+		ctx, cancelTimeout = gocontext.WithTimeout(ctx, timeout)
+		cancelParent := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelParent()
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		select {
+		case sig := <-signals:
+			logger.Infof("received signal %s, cancelling", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
 }
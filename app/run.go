@@ -1,6 +1,9 @@
 package app
 
 import (
+	gocontext "context"
+	"time"
+
 	"github.com/gulien/orbit/app/context"
 	"github.com/gulien/orbit/app/runner"
 
@@ -11,6 +14,25 @@ import (
 const orbitFilePath = "orbit.yml"
 
 var (
+	// junitFilePath is the path of the optional JUnit XML report.
+	junitFilePath string
+
+	// logDir is the optional directory where the output of each task is written.
+	logDir string
+
+	// assumeYes automatically answers the prompts of the tasks if true.
+	assumeYes bool
+
+	// lockTimeout is how long a task waits for a lock held by another process.
+	lockTimeout time.Duration
+
+	// noCache disables the cache of the files generated by the tasks if true.
+	noCache bool
+
+	// overrides represents the values which take precedence over the payload and the variables.
+	// Value format: key=value.
+	overrides []string
+
 	// runCmd is the instance of run command.
 	runCmd = &cobra.Command{
 		Use:           "run",
@@ -22,27 +44,32 @@ var (
 	}
 )
 
-// init initializes a runCmd instance and adds it to the RootCmd.
+// init initializes a runCmd instance with some flags and adds it to the RootCmd.
 func init() {
+	runCmd.Flags().StringVar(&junitFilePath, "junit", "", "specify the output file of a JUnit XML report of the executed tasks")
+	runCmd.Flags().StringVar(&logDir, "log-dir", "", "specify a directory where the output of each task is written into a file named after the task")
+	runCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "automatically confirm the tasks and use the default values of their inputs")
+	runCmd.Flags().BoolVar(&noCache, "no-cache", false, "run the tasks without restoring nor storing their generated files in the cache")
+	runCmd.Flags().StringArrayVar(&overrides, "set", nil, "override a value of the payload or a variable (key=value, may be repeated)")
+	runCmd.Flags().DurationVar(&lockTimeout, "lock-timeout", 0, "specify how long a locked task waits for the lock held by another process (e.g. 30s)")
 	RootCmd.AddCommand(runCmd)
 }
 
 // run runs one or more tasks defined in a configuration file.
 func run(cmd *cobra.Command, args []string) error {
-	// alright, let's instantiate our Orbit context...
-	if templateFilePath == "" {
-		templateFilePath = orbitFilePath
-	}
+	ctx, cancel := newContext()
+	defer cancel()
 
-	ctx, err := context.NewOrbitContext(templateFilePath, payload, templates, nil)
+	r, err := newOrbitRunner(ctx)
 	if err != nil {
 		return err
 	}
 
-	// then our runner...
-	r, err := runner.NewOrbitRunner(ctx)
-	if err != nil {
-		return err
+	r.SetLogDir(logDir)
+	r.SetAssumeYes(assumeYes)
+	r.SetLockTimeout(lockTimeout)
+	if noCache {
+		r.SetCacheDir("")
 	}
 
 	// if no args, prints the available tasks to Stdout...
@@ -52,5 +79,34 @@ func run(cmd *cobra.Command, args []string) error {
 	}
 
 	// ... or runs given tasks.
-	return r.Run(args[:]...)
+	err = r.Run(ctx, args[:]...)
+
+	// the report is written even if a task has failed.
+	if junitFilePath != "" {
+		if reportErr := r.WriteJUnitReport(junitFilePath); reportErr != nil && err == nil {
+			return reportErr
+		}
+	}
+
+	return err
+}
+
+// newOrbitRunner instantiates an OrbitRunner from the configuration file given by the user.
+func newOrbitRunner(ctx gocontext.Context) (*runner.OrbitRunner, error) {
+	// alright, let's instantiate our Orbit context...
+	if templateFilePath == "" {
+		templateFilePath = orbitFilePath
+	}
+
+	orbitContext, err := context.NewOrbitContext(ctx, templateFilePath, payload, templates, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if orbitContext.Overrides, err = context.ParseOverrides(overrides); err != nil {
+		return nil, err
+	}
+
+	// then our runner.
+	return runner.NewOrbitRunner(ctx, orbitContext)
 }
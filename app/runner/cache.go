@@ -0,0 +1,347 @@
+package runner
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
+	"github.com/gulien/orbit/app/logger"
+)
+
+// cacheKeyVersion is part of every cache key, so that changing the way the keys are computed invalidates the cache.
+const cacheKeyVersion = "orbit-cache-v1"
+
+// SetCacheDir sets the directory of the cache of the generated files. An empty directory disables the cache.
+func (r *OrbitRunner) SetCacheDir(dir string) {
+	if dir == "" {
+		r.cache = nil
+		return
+	}
+
+	r.cache = NewOrbitCache(dir)
+}
+
+/*
+restoreFromCache restores the files generated by the given task
+if its key is in the local cache or in the remote cache.
+
+Returns the key of the task (or an empty key if the task is not cached),
+the number of files which had to be restored and true if the task is up to date.
+*/
+func (r *OrbitRunner) restoreFromCache(ctx gocontext.Context, task *orbitTask, env []string) (string, int, bool) {
+	if r.cache == nil || !task.Cache {
+		return "", 0, false
+	}
+
+	key, err := r.cacheKey(ctx, task, env)
+	if err != nil {
+		logger.Infof("unable to compute the cache key of task %s, running it. Details:\n%s", task.Use, err)
+		return "", 0, false
+	}
+
+	entry, err := r.cache.get(key)
+	if err != nil {
+		logger.Infof("unable to read the cache entry %s of task %s, running it. Details:\n%s", key, task.Use, err)
+		return key, 0, false
+	}
+
+	if entry == nil && r.remote != nil {
+		entry = r.fetchFromRemote(ctx, task, key)
+	}
+
+	if entry == nil {
+		logger.Debugf("task %s is not in the cache (key %s)", task.Use, key)
+		return key, 0, false
+	}
+
+	restored := 0
+	for _, file := range entry.Files {
+		copied, err := r.restoreFile(file)
+		if err != nil {
+			logger.Infof("unable to restore the file %s of task %s from the cache, running it. Details:\n%s", file.Path, task.Use, err)
+			return key, 0, false
+		}
+
+		if copied {
+			restored++
+		}
+	}
+
+	logger.Infof("task %s is up to date, %d of %d file(s) restored from the cache (key %s)", task.Use, restored, len(entry.Files), key)
+
+	return key, restored, true
+}
+
+// restoreFile restores the given file from the cache, unless it is already up to date. Returns true if the file has been copied.
+func (r *OrbitRunner) restoreFile(file *orbitCacheFile) (bool, error) {
+	path := filepath.Join(r.dir, filepath.FromSlash(file.Path))
+	if digest, err := digestFile(path); err == nil && digest == file.Digest {
+		return false, os.Chmod(path, file.Mode)
+	}
+
+	src, err := r.cache.open(file.Digest)
+	if err != nil {
+		return false, err
+	}
+
+	defer src.Close()
+
+	return true, helpers.CopyFileAtomic(path, src, file.Mode, nil)
+}
+
+/*
+storeInCache stores the files generated by the given task under the given key,
+in the local cache and in the remote cache if it is writable.
+*/
+func (r *OrbitRunner) storeInCache(ctx gocontext.Context, task *orbitTask, key string) {
+	paths, unmatched, err := r.expand(task.Generates)
+	if err != nil {
+		logger.Infof("unable to find the files generated by task %s. Details:\n%s", task.Use, err)
+		return
+	}
+
+	// an entry without some of the generated files would restore an incomplete state.
+	if len(unmatched) > 0 {
+		logger.Error(OrbitError.NewOrbitErrorf("task %s has not been stored in the cache as it has not generated any file matching %s", task.Use, strings.Join(unmatched, ", ")))
+		return
+	}
+
+	entry := &orbitCacheEntry{}
+	for _, path := range paths {
+		file, err := r.storeFile(path)
+		if err != nil {
+			logger.Infof("unable to store the file %s generated by task %s in the cache. Details:\n%s", path, task.Use, err)
+			return
+		}
+
+		entry.Files = append(entry.Files, file)
+	}
+
+	if err := r.cache.putEntry(key, entry); err != nil {
+		logger.Infof("unable to store task %s in the cache. Details:\n%s", task.Use, err)
+		return
+	}
+
+	logger.Debugf("%d file(s) generated by task %s have been stored in the cache (key %s)", len(entry.Files), task.Use, key)
+
+	if r.remote != nil && r.remote.write {
+		r.pushToRemote(ctx, task, key, entry)
+	}
+}
+
+// storeFile stores the given generated file in the cache.
+func (r *OrbitRunner) storeFile(path string) (*orbitCacheFile, error) {
+	fullPath := filepath.Join(r.dir, filepath.FromSlash(path))
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := digestFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.cache.putFile(digest, fullPath); err != nil {
+		return nil, err
+	}
+
+	return &orbitCacheFile{Path: path, Digest: digest, Mode: info.Mode().Perm()}, nil
+}
+
+/*
+checkCache returns an error if the given task may be cached but does not declare its sources
+or its generated files: a task without sources would always be restored from the cache,
+even once the files it reads have changed.
+*/
+func checkCache(task *orbitTask) error {
+	if !task.Cache {
+		return nil
+	}
+
+	if len(task.Sources) == 0 || len(task.Generates) == 0 {
+		return OrbitError.NewOrbitErrorf("task %s cannot be cached as it does not declare its sources and its generated files", task.Use)
+	}
+
+	return nil
+}
+
+/*
+cacheKey computes the key of the given task from its shell, its commands,
+the patterns of its generated files, the values of its environment variables
+(including its inputs), the content of its sources and the keys of the tasks it calls.
+*/
+func (r *OrbitRunner) cacheKey(ctx gocontext.Context, task *orbitTask, env []string) (string, error) {
+	return r.taskKey(ctx, task, env, map[string]bool{task.Use: true})
+}
+
+// taskKey computes the key of the given task, the given path containing the tasks calling it.
+func (r *OrbitRunner) taskKey(ctx gocontext.Context, task *orbitTask, env []string, path map[string]bool) (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\nshell:%s\n", cacheKeyVersion, task.Shell)
+
+	for _, cmd := range task.Run {
+		fmt.Fprintf(h, "run:%q\n", cmd)
+
+		// the files generated by the task also depend on the tasks it calls.
+		for _, name := range r.interpret(cmd) {
+			called := r.getTask(name)
+			switch {
+			case called == nil:
+				fmt.Fprintf(h, "call:%q:missing\n", name)
+				continue
+			case path[name]:
+				fmt.Fprintf(h, "call:%q:cycle\n", name)
+				continue
+			}
+
+			if err := r.renderTask(ctx, called); err != nil {
+				return "", err
+			}
+
+			path[name] = true
+			key, err := r.taskKey(ctx, called, env, path)
+			delete(path, name)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "call:%q:%s\n", name, key)
+		}
+	}
+
+	for _, pattern := range task.Generates {
+		fmt.Fprintf(h, "generates:%q\n", pattern)
+	}
+
+	values := r.cacheEnv(task, env)
+	for _, value := range values {
+		fmt.Fprintf(h, "env:%q\n", value)
+	}
+
+	for _, pattern := range task.Sources {
+		fmt.Fprintf(h, "sources:%q\n", pattern)
+	}
+
+	sources, unmatched, err := r.expand(task.Sources)
+	if err != nil {
+		return "", err
+	}
+
+	// a missing source is part of the key, but it is likely a mistake.
+	for _, pattern := range unmatched {
+		logger.Infof("sources pattern %s of task %s does not match any file", pattern, task.Use)
+	}
+
+	for _, path := range sources {
+		digest, err := digestFile(filepath.Join(r.dir, filepath.FromSlash(path)))
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "source:%q:%s\n", path, digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheEnv returns the sorted environment variables of the given task which are part of its cache key.
+func (r *OrbitRunner) cacheEnv(task *orbitTask, env []string) []string {
+	values := make(map[string]string)
+	for _, variables := range [][]string{os.Environ(), r.env, env} {
+		for _, variable := range variables {
+			parts := strings.SplitN(variable, "=", 2)
+			if len(parts) == 2 {
+				values[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	// the inputs are always part of the key.
+	names := append([]string{}, task.Env...)
+	for _, input := range task.Inputs {
+		names = append(names, input.Name)
+	}
+
+	var result []string
+	for _, name := range names {
+		result = append(result, name+"="+values[name])
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+/*
+expand returns the sorted slash-separated paths of the files matching the given patterns,
+relative to the working directory, and the patterns which do not match any file.
+
+A relative pattern is relative to the working directory, while an absolute pattern is used
+as is. A pattern matching a directory matches all its files. The patterns follow the syntax
+of filepath.Match: "**" matches a single path element, like "*".
+*/
+func (r *OrbitRunner) expand(patterns []string) ([]string, []string, error) {
+	base, err := filepath.Abs(r.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unmatched []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		glob := filepath.FromSlash(pattern)
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(base, glob)
+		}
+
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files := 0
+		for _, match := range matches {
+			err := filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+
+				rel, err := filepath.Rel(base, path)
+				if err != nil {
+					return err
+				}
+
+				seen[filepath.ToSlash(rel)] = true
+				files++
+				return nil
+			})
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if files == 0 {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, unmatched, nil
+}
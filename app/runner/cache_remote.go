@@ -0,0 +1,299 @@
+package runner
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
+	"github.com/gulien/orbit/app/logger"
+)
+
+const (
+	// readRemoteCacheMode only fetches the generated files from the remote cache.
+	readRemoteCacheMode = "read"
+
+	// writeRemoteCacheMode fetches the generated files from the remote cache and stores them into it.
+	writeRemoteCacheMode = "write"
+
+	// remoteCacheTimeout is the maximum duration of a request to the remote cache.
+	remoteCacheTimeout = 30 * time.Second
+)
+
+type (
+	// orbitRemoteCacheConfig represents the remote cache as defined in the configuration file.
+	orbitRemoteCacheConfig struct {
+		// URL is the base URL of the remote cache.
+		URL string `yaml:"url"`
+
+		// Mode is either read (default) or write.
+		Mode string `yaml:"mode,omitempty"`
+	}
+
+	/*
+		orbitRemoteCache is a cache of the files generated by the tasks shared over HTTP.
+
+		It follows the URL layout of the Bazel HTTP remote cache: the entries are
+		fetched with GET and stored with PUT under <url>/ac/<key>, the files
+		under <url>/cas/<sha256 of their content>. Unlike Bazel, the entries
+		are JSON documents, so both tools may not share their entries and the
+		server must not validate them as ActionResult protocol buffers.
+	*/
+	orbitRemoteCache struct {
+		// url is the base URL of the remote cache, without trailing slash.
+		url string
+
+		// write is true if the generated files are stored into the remote cache.
+		write bool
+
+		// client is the HTTP client sending the requests.
+		client *http.Client
+	}
+)
+
+// newOrbitRemoteCache creates an instance of orbitRemoteCache from the given configuration.
+func newOrbitRemoteCache(config *orbitRemoteCacheConfig) (*orbitRemoteCache, error) {
+	if config.URL == "" {
+		return nil, OrbitError.NewOrbitErrorf("remote cache has no url")
+	}
+
+	c := &orbitRemoteCache{
+		url:    strings.TrimSuffix(config.URL, "/"),
+		client: &http.Client{Timeout: remoteCacheTimeout},
+	}
+
+	switch config.Mode {
+	case "", readRemoteCacheMode:
+	case writeRemoteCacheMode:
+		c.write = true
+	default:
+		return nil, OrbitError.NewOrbitErrorf("remote cache mode %s is not supported, expected %s or %s", config.Mode, readRemoteCacheMode, writeRemoteCacheMode)
+	}
+
+	return c, nil
+}
+
+// getEntry returns the entry of the given key or nil if not found.
+func (c *orbitRemoteCache) getEntry(ctx gocontext.Context, key string) (*orbitCacheEntry, error) {
+	body, err := c.get(ctx, actionCacheDir, key)
+	if body == nil || err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	entry := &orbitCacheEntry{}
+	if err := json.NewDecoder(body).Decode(entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// getFile fetches the file of the given digest into the given local cache.
+func (c *orbitRemoteCache) getFile(ctx gocontext.Context, digest string, local *OrbitCache) error {
+	body, err := c.get(ctx, contentCacheDir, digest)
+	if err != nil {
+		return err
+	}
+
+	if body == nil {
+		return fmt.Errorf("file %s is not in the remote cache", digest)
+	}
+
+	defer body.Close()
+
+	// the content is checked before being moved into the local cache.
+	h := sha256.New()
+	path := filepath.Join(local.dir, contentCacheDir, digest)
+
+	return helpers.CopyFileAtomic(path, io.TeeReader(body, h), 0644, func() error {
+		if hex.EncodeToString(h.Sum(nil)) != digest {
+			return fmt.Errorf("file %s of the remote cache is corrupted", digest)
+		}
+
+		return nil
+	})
+}
+
+// putEntry stores the given entry under the given key.
+func (c *orbitRemoteCache) putEntry(ctx gocontext.Context, key string, entry *orbitCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return c.put(ctx, actionCacheDir, key, bytes.NewReader(data), int64(len(data)))
+}
+
+// putFile stores the content of the given file under the given digest.
+func (c *orbitRemoteCache) putFile(ctx gocontext.Context, digest string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return c.put(ctx, contentCacheDir, digest, file, info.Size())
+}
+
+// get sends a GET request for the given name. Returns a nil body if not found.
+func (c *orbitRemoteCache) get(ctx gocontext.Context, dir string, name string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+"/"+dir+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, nil
+	default:
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %s", req.URL, res.Status)
+	}
+}
+
+// put sends a PUT request with the given content for the given name.
+func (c *orbitRemoteCache) put(ctx gocontext.Context, dir string, name string, body io.Reader, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, c.url+"/"+dir+"/"+name, body)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("PUT %s returned %s", req.URL, res.Status)
+	}
+
+	return nil
+}
+
+// check returns an error if the entry references a file outside of the working directory or an invalid digest.
+func (entry *orbitCacheEntry) check() error {
+	for _, file := range entry.Files {
+		path := filepath.ToSlash(filepath.Clean(filepath.FromSlash(file.Path)))
+		if filepath.IsAbs(file.Path) || path == ".." || strings.HasPrefix(path, "../") {
+			return fmt.Errorf("file %s is outside of the working directory", file.Path)
+		}
+
+		if _, err := hex.DecodeString(file.Digest); err != nil || len(file.Digest) != sha256.Size*2 {
+			return fmt.Errorf("digest %s of file %s is not a SHA-256", file.Digest, file.Path)
+		}
+	}
+
+	return nil
+}
+
+// configureRemoteCache creates the remote cache from the configuration file, if any.
+func (r *OrbitRunner) configureRemoteCache() error {
+	if r.config.RemoteCache == nil {
+		return nil
+	}
+
+	remote, err := newOrbitRemoteCache(r.config.RemoteCache)
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to configure the remote cache of configuration file %s. Details:\n%s", r.context.TemplateFilePath, err)
+	}
+
+	r.remote = remote
+	logger.Debugf("remote cache %s has been configured (write: %t)", remote.url, remote.write)
+
+	return nil
+}
+
+// fetchFromRemote fetches the entry of the given key and its files into the local cache.
+func (r *OrbitRunner) fetchFromRemote(ctx gocontext.Context, task *orbitTask, key string) *orbitCacheEntry {
+	entry, err := r.remote.getEntry(ctx, key)
+	if err != nil {
+		r.disableRemote(err)
+		return nil
+	}
+
+	if entry == nil {
+		logger.Debugf("task %s is not in the remote cache (key %s)", task.Use, key)
+		return nil
+	}
+
+	if err := entry.check(); err != nil {
+		r.disableRemote(err)
+		return nil
+	}
+
+	for _, file := range entry.Files {
+		if r.cache.has(file.Digest) {
+			continue
+		}
+
+		if err := r.remote.getFile(ctx, file.Digest, r.cache); err != nil {
+			r.disableRemote(err)
+			return nil
+		}
+	}
+
+	if err := r.cache.putEntry(key, entry); err != nil {
+		logger.Infof("unable to store the entry %s of task %s fetched from the remote cache. Details:\n%s", key, task.Use, err)
+	}
+
+	logger.Debugf("task %s has been fetched from the remote cache (key %s)", task.Use, key)
+
+	return entry
+}
+
+// pushToRemote stores the given entry of the given task and its files into the remote cache.
+func (r *OrbitRunner) pushToRemote(ctx gocontext.Context, task *orbitTask, key string, entry *orbitCacheEntry) {
+	for _, file := range entry.Files {
+		src := filepath.Join(r.cache.dir, contentCacheDir, file.Digest)
+		if err := r.remote.putFile(ctx, file.Digest, src); err != nil {
+			r.disableRemote(err)
+			return
+		}
+	}
+
+	// the entry is stored last, so that it never references missing files.
+	if err := r.remote.putEntry(ctx, key, entry); err != nil {
+		r.disableRemote(err)
+		return
+	}
+
+	logger.Debugf("%d file(s) generated by task %s have been stored in the remote cache (key %s)", len(entry.Files), task.Use, key)
+}
+
+// disableRemote disables the remote cache for the remaining tasks, after the given failure.
+func (r *OrbitRunner) disableRemote(err error) {
+	logger.Infof("remote cache %s is unavailable, using the local cache only. Details:\n%s", r.remote.url, err)
+	r.remote = nil
+}
@@ -0,0 +1,301 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/helpers"
+)
+
+const (
+	// cacheDirEnvVariable is the environment variable which overrides the default cache directory.
+	cacheDirEnvVariable = "ORBIT_CACHE_DIR"
+
+	// actionCacheDir is the directory of the cache entries, named after their keys.
+	actionCacheDir = "ac"
+
+	// contentCacheDir is the directory of the cached files, named after the SHA-256 of their content.
+	contentCacheDir = "cas"
+)
+
+type (
+	/*
+		OrbitCache is a content-addressed cache of the files generated by the tasks.
+
+		Its layout follows the one of the Bazel HTTP remote cache: the entries are
+		stored under "ac" and named after the keys of the tasks, the files are stored
+		under "cas" and named after the SHA-256 of their content.
+	*/
+	OrbitCache struct {
+		// dir is the root directory of the cache.
+		dir string
+	}
+
+	// orbitCacheEntry lists the files generated by a task.
+	orbitCacheEntry struct {
+		// Files array contains the generated files.
+		Files []*orbitCacheFile `json:"files"`
+	}
+
+	// orbitCacheFile is a file generated by a task.
+	orbitCacheFile struct {
+		// Path is the slash-separated path of the file, relative to the working directory.
+		Path string `json:"path"`
+
+		// Digest is the SHA-256 of the content of the file.
+		Digest string `json:"digest"`
+
+		// Mode is the permission bits of the file.
+		Mode os.FileMode `json:"mode"`
+	}
+
+	// OrbitCacheStats contains statistics about a cache.
+	OrbitCacheStats struct {
+		// Entries is the number of cached tasks.
+		Entries int
+
+		// Files is the number of cached files.
+		Files int
+
+		// Size is the total size of the cache in bytes.
+		Size int64
+	}
+)
+
+// NewOrbitCache creates an instance of OrbitCache stored in the given directory.
+func NewOrbitCache(dir string) *OrbitCache {
+	return &OrbitCache{dir: dir}
+}
+
+/*
+DefaultCacheDir returns the default directory of the cache:
+the ORBIT_CACHE_DIR environment variable if set, otherwise
+orbit under the user cache directory (e.g. ~/.cache/orbit).
+*/
+func DefaultCacheDir() string {
+	if dir := os.Getenv(cacheDirEnvVariable); dir != "" {
+		return dir
+	}
+
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "orbit")
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "orbit")
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".cache", "orbit")
+}
+
+// Dir returns the root directory of the cache.
+func (c *OrbitCache) Dir() string {
+	return c.dir
+}
+
+// get returns the entry of the given key or nil if not found.
+func (c *OrbitCache) get(key string) (*orbitCacheEntry, error) {
+	path := filepath.Join(c.dir, actionCacheDir, key)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &orbitCacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+
+	// the modification time of an entry is the last time it has been used.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return entry, nil
+}
+
+// has returns true if the file of the given digest is in the cache.
+func (c *OrbitCache) has(digest string) bool {
+	_, err := os.Stat(filepath.Join(c.dir, contentCacheDir, digest))
+	return err == nil
+}
+
+// open opens the file of the given digest.
+func (c *OrbitCache) open(digest string) (*os.File, error) {
+	return os.Open(filepath.Join(c.dir, contentCacheDir, digest))
+}
+
+// putEntry stores the given entry under the given key.
+func (c *OrbitCache) putEntry(key string, entry *orbitCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return helpers.WriteFileAtomic(filepath.Join(c.dir, actionCacheDir, key), data, 0644)
+}
+
+// putFile stores the content of the given file under the given digest, if not already done.
+func (c *OrbitCache) putFile(digest string, path string) error {
+	if c.has(digest) {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	return helpers.CopyFileAtomic(filepath.Join(c.dir, contentCacheDir, digest), src, 0644, nil)
+}
+
+// Stats returns statistics about the cache.
+func (c *OrbitCache) Stats() (*OrbitCacheStats, error) {
+	stats := &OrbitCacheStats{}
+
+	entries, err := c.list(actionCacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := c.list(contentCacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Entries = len(entries)
+	stats.Files = len(files)
+	for _, info := range append(entries, files...) {
+		stats.Size += info.Size()
+	}
+
+	return stats, nil
+}
+
+// Clean removes everything from the cache.
+func (c *OrbitCache) Clean() error {
+	for _, dir := range []string{actionCacheDir, contentCacheDir} {
+		if err := os.RemoveAll(filepath.Join(c.dir, dir)); err != nil {
+			return OrbitError.NewOrbitErrorf("unable to clean the cache %s. Details:\n%s", c.dir, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+Prune removes the entries which have not been used for the given duration,
+then the files which are not referenced by the remaining entries.
+
+Returns the number of removed entries.
+*/
+func (c *OrbitCache) Prune(olderThan time.Duration) (int, error) {
+	entries, err := c.list(actionCacheDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		removed    int
+		referenced = make(map[string]bool)
+		limit      = time.Now().Add(-olderThan)
+	)
+
+	for _, info := range entries {
+		path := filepath.Join(c.dir, actionCacheDir, info.Name())
+		if info.ModTime().Before(limit) {
+			if err := os.Remove(path); err != nil {
+				return removed, OrbitError.NewOrbitErrorf("unable to remove the cache entry %s. Details:\n%s", path, err)
+			}
+
+			removed++
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return removed, OrbitError.NewOrbitErrorf("unable to read the cache entry %s. Details:\n%s", path, err)
+		}
+
+		entry := &orbitCacheEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			// a broken entry is useless.
+			os.Remove(path)
+			removed++
+			continue
+		}
+
+		for _, file := range entry.Files {
+			referenced[file.Digest] = true
+		}
+	}
+
+	files, err := c.list(contentCacheDir)
+	if err != nil {
+		return removed, err
+	}
+
+	for _, info := range files {
+		if !referenced[info.Name()] {
+			path := filepath.Join(c.dir, contentCacheDir, info.Name())
+			if err := os.Remove(path); err != nil {
+				return removed, OrbitError.NewOrbitErrorf("unable to remove the cached file %s. Details:\n%s", path, err)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// list returns the files of the given directory of the cache, except the ones being written.
+func (c *OrbitCache) list(dir string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(c.dir, dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to read the cache %s. Details:\n%s", c.dir, err)
+	}
+
+	var files []os.FileInfo
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), helpers.TempFilePrefix) {
+			files = append(files, info)
+		}
+	}
+
+	return files, nil
+}
+
+// digestFile returns the SHA-256 of the content of the given file.
+func digestFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,320 @@
+package runner
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gulien/orbit/app/context"
+)
+
+// Tests if the files generated by a task are restored from the cache
+// when its sources have not changed.
+func TestCache(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit-project")
+	defer os.RemoveAll(dir)
+
+	cacheDir, _ := ioutil.TempDir("", "orbit-cache")
+	defer os.RemoveAll(cacheDir)
+
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "src", "spacex.txt"), []byte("Falcon 9\n"), 0644)
+
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit-cache.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+	r.SetStdio(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	r.SetDir(dir)
+	r.SetCacheDir(cacheDir)
+
+	builds := func() int {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "build.log"))
+		return strings.Count(string(data), "built")
+	}
+
+	// case 1: uses an empty cache.
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 1 {
+		t.Error("Task should have been run!")
+	}
+
+	// case 2: uses the same sources, without the generated files.
+	os.RemoveAll(filepath.Join(dir, "dist"))
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 1 {
+		t.Error("Task should have been restored from the cache!")
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "dist", "launchers.txt")); string(data) != "Falcon 9\n" {
+		t.Errorf("Generated file should have been restored, got %q!", string(data))
+	}
+
+	// case 3: uses modified sources (e.g. another branch).
+	ioutil.WriteFile(filepath.Join(dir, "src", "spacex.txt"), []byte("Falcon Heavy\n"), 0644)
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 2 {
+		t.Error("Task should have been run with the modified sources!")
+	}
+
+	// case 4: uses the previous sources (e.g. back to the previous branch).
+	ioutil.WriteFile(filepath.Join(dir, "src", "spacex.txt"), []byte("Falcon 9\n"), 0644)
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 2 {
+		t.Error("Task should have been restored from the cache!")
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "dist", "launchers.txt")); string(data) != "Falcon 9\n" {
+		t.Errorf("Generated file of the previous sources should have been restored, got %q!", string(data))
+	}
+
+	// case 5: uses another value of an environment variable of the key.
+	r.SetEnv([]string{"ORBIT_TARGET=mars"})
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 3 {
+		t.Error("Task should have been run with another environment variable!")
+	}
+
+	// case 6: uses a task calling another task whose definition has changed.
+	build, pkg := r.getTask("build"), r.getTask("package")
+	r.renderTask(gocontext.Background(), pkg)
+	key, err := r.cacheKey(gocontext.Background(), pkg, nil)
+	build.Run = append(build.Run, "echo again")
+	if other, _ := r.cacheKey(gocontext.Background(), pkg, nil); err != nil || other == key {
+		t.Error("Cache key should have changed with the definition of the called task!")
+	}
+
+	build.Run = build.Run[:len(build.Run)-1]
+
+	// case 7: uses a task which is not cached.
+	if err := r.Run(gocontext.Background(), "uncached"); err != nil || builds() != 4 {
+		t.Error("Task should have been run as it is not cached!")
+	}
+
+	if err := r.Run(gocontext.Background(), "uncached"); err != nil || builds() != 5 {
+		t.Error("Task should have been run again as it is not cached!")
+	}
+
+	// case 8: uses a cached task without sources.
+	if err := r.Run(gocontext.Background(), "unsourced"); err == nil || builds() != 5 {
+		t.Error("Task should not have been run without sources!")
+	}
+
+	// case 9: uses a task which does not generate its files.
+	if err := r.Run(gocontext.Background(), "ungenerated"); err != nil || builds() != 6 {
+		t.Error("Task should have been run!")
+	}
+
+	if err := r.Run(gocontext.Background(), "ungenerated"); err != nil || builds() != 7 {
+		t.Error("Task should have been run again as it has not been stored in the cache!")
+	}
+
+	// case 10: uses absolute patterns.
+	paths, unmatched, err := r.expand([]string{filepath.Join(dir, "src", "*.txt"), filepath.Join(dir, "nothing")})
+	if err != nil || strings.Join(paths, ",") != "src/spacex.txt" || len(unmatched) != 1 {
+		t.Errorf("Absolute patterns should have been used as is, got %v and %v!", paths, unmatched)
+	}
+
+	// case 11: uses a disabled cache.
+	r.SetCacheDir("")
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 8 {
+		t.Error("Task should have been run without cache!")
+	}
+}
+
+// Tests the statistics, the pruning and the cleaning of a cache.
+func TestOrbitCache(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "orbit-project")
+	defer os.RemoveAll(dir)
+
+	cacheDir, _ := ioutil.TempDir("", "orbit-cache")
+	defer os.RemoveAll(cacheDir)
+
+	ioutil.WriteFile(filepath.Join(dir, "falcon.txt"), []byte("Falcon 9"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "heavy.txt"), []byte("Falcon Heavy"), 0644)
+
+	c := NewOrbitCache(cacheDir)
+	for key, name := range map[string]string{"falcon": "falcon.txt", "heavy": "heavy.txt"} {
+		digest, _ := digestFile(filepath.Join(dir, name))
+		c.putFile(digest, filepath.Join(dir, name))
+		c.putEntry(key, &orbitCacheEntry{Files: []*orbitCacheFile{{Path: name, Digest: digest, Mode: 0644}}})
+	}
+
+	// case 1: uses a cache with two entries.
+	stats, err := c.Stats()
+	if err != nil || stats.Entries != 2 || stats.Files != 2 || stats.Size == 0 {
+		t.Errorf("Cache should contain two entries and two files, got %+v!", stats)
+	}
+
+	// case 2: prunes the entries which have not been used for an hour.
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(cacheDir, actionCacheDir, "heavy"), old, old)
+	if removed, err := c.Prune(time.Hour); err != nil || removed != 1 {
+		t.Error("Cache should have removed one entry!")
+	}
+
+	if stats, _ := c.Stats(); stats.Entries != 1 || stats.Files != 1 {
+		t.Errorf("Cache should contain one entry and one file, got %+v!", stats)
+	}
+
+	// case 3: cleans the cache.
+	if err := c.Clean(); err != nil {
+		t.Error("Cache should have been cleaned!")
+	}
+
+	if stats, _ := c.Stats(); stats.Entries != 0 || stats.Files != 0 {
+		t.Errorf("Cache should be empty, got %+v!", stats)
+	}
+}
+
+// Tests if the files generated by a task are fetched from and stored into
+// a remote cache, and if a failing remote cache does not fail the task.
+func TestRemoteCache(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		stored  = make(map[string][]byte)
+		failing bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			data, ok := stored[req.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Write(data)
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(req.Body)
+			stored[req.URL.Path] = data
+		}
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "orbit-project")
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "src", "spacex.txt"), []byte("Falcon 9\n"), 0644)
+
+	var cacheDirs []string
+	defer func() {
+		for _, cacheDir := range cacheDirs {
+			os.RemoveAll(cacheDir)
+		}
+	}()
+
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit-remote-cache.yml")
+	newRunner := func(mode string) *OrbitRunner {
+		// each runner uses its own local cache, like another machine.
+		cacheDir, _ := ioutil.TempDir("", "orbit-cache")
+		cacheDirs = append(cacheDirs, cacheDir)
+
+		ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+		ctx.Overrides = map[string]interface{}{"cache_url": server.URL, "cache_mode": mode}
+		r, err := NewOrbitRunner(gocontext.Background(), ctx)
+		if err != nil {
+			t.Fatalf("Runner should have been instantiated, got %s!", err)
+		}
+
+		r.SetStdio(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+		r.SetDir(dir)
+		r.SetCacheDir(cacheDir)
+
+		return r
+	}
+
+	builds := func() int {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "build.log"))
+		return strings.Count(string(data), "built")
+	}
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(stored)
+	}
+
+	// case 1: uses an unknown mode.
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	ctx.Overrides = map[string]interface{}{"cache_url": server.URL, "cache_mode": "append"}
+	if _, err := NewOrbitRunner(gocontext.Background(), ctx); err == nil {
+		t.Error("Runner should not have been instantiated with an unknown mode!")
+	}
+
+	// case 2: uses an empty remote cache in read mode.
+	if err := newRunner("read").Run(gocontext.Background(), "build"); err != nil || builds() != 1 {
+		t.Error("Task should have been run!")
+	}
+
+	if count() != 0 {
+		t.Error("Remote cache should not have been written in read mode!")
+	}
+
+	// case 3: uses an empty remote cache in write mode (e.g. on CI).
+	if err := newRunner("write").Run(gocontext.Background(), "build"); err != nil || builds() != 2 {
+		t.Error("Task should have been run!")
+	}
+
+	if count() != 2 {
+		t.Errorf("Remote cache should contain an entry and a file, got %d item(s)!", count())
+	}
+
+	// case 4: uses a remote cache containing the task with an empty local cache (e.g. on a developer machine).
+	os.RemoveAll(filepath.Join(dir, "dist"))
+	if err := newRunner("read").Run(gocontext.Background(), "build"); err != nil || builds() != 2 {
+		t.Error("Task should have been restored from the remote cache!")
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "dist", "launchers.txt")); string(data) != "Falcon 9\n" {
+		t.Errorf("Generated file should have been restored, got %q!", string(data))
+	}
+
+	// case 5: uses a remote cache containing a corrupted file.
+	mu.Lock()
+	for path := range stored {
+		if strings.Contains(path, "/"+contentCacheDir+"/") {
+			stored[path] = []byte("Falcon 1\n")
+		}
+	}
+	mu.Unlock()
+
+	os.RemoveAll(filepath.Join(dir, "dist"))
+	r := newRunner("read")
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 3 {
+		t.Error("Task should have been run instead of being restored from a corrupted file!")
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(r.cache.Dir(), contentCacheDir))
+	for _, file := range files {
+		if data, _ := ioutil.ReadFile(filepath.Join(r.cache.Dir(), contentCacheDir, file.Name())); string(data) != "Falcon 9\n" {
+			t.Errorf("Corrupted file should not have been stored in the local cache, got %q!", string(data))
+		}
+	}
+
+	// case 6: uses a failing remote cache.
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+
+	r = newRunner("write")
+	if err := r.Run(gocontext.Background(), "build"); err != nil || builds() != 4 {
+		t.Error("Task should have been run despite the failing remote cache!")
+	}
+
+	if r.remote != nil {
+		t.Error("Failing remote cache should have been disabled!")
+	}
+}
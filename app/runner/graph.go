@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+const (
+	// TreeGraphFormat renders the tasks as an ASCII tree.
+	TreeGraphFormat = "tree"
+
+	// DOTGraphFormat renders the tasks as a Graphviz DOT graph.
+	DOTGraphFormat = "dot"
+
+	// MermaidGraphFormat renders the tasks as a Mermaid flowchart.
+	MermaidGraphFormat = "mermaid"
+)
+
+/*
+Graph prints the graph of the given tasks and of the tasks they call
+to the given writer. If no task given, prints the graph of all the tasks:
+a task which cannot be rendered (e.g. because of a missing data) is then
+skipped, so that it does not prevent the others tasks from being printed.
+
+The format may be tree, dot or mermaid.
+*/
+func (r *OrbitRunner) Graph(ctx gocontext.Context, w io.Writer, format string, names ...string) error {
+	tasks, err := r.selectTasks(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "", TreeGraphFormat:
+		for _, task := range r.roots(tasks, names) {
+			r.printTree(w, task.Use, "", "", make(map[string]bool))
+		}
+	case DOTGraphFormat:
+		r.printDOT(w, tasks)
+	case MermaidGraphFormat:
+		r.printMermaid(w, tasks)
+	default:
+		return OrbitError.NewOrbitErrorf("unknown graph format %s. Available formats are %s, %s and %s", format, TreeGraphFormat, DOTGraphFormat, MermaidGraphFormat)
+	}
+
+	return nil
+}
+
+/*
+Explain prints the definition of the given task to the given writer:
+where it comes from, its attributes, its commands once the configuration
+file has been rendered and the tasks it calls in order of execution.
+
+As the rendered values may contain secrets, they are masked.
+*/
+func (r *OrbitRunner) Explain(ctx gocontext.Context, w io.Writer, name string) error {
+	task := r.getTask(name)
+	if task == nil {
+		return OrbitError.NewOrbitErrorf("task %s does not exist in configuration file %s", name, r.context.TemplateFilePath)
+	}
+
+	// renders the task and the tasks it calls.
+	if _, err := r.selectTasks(ctx, []string{name}); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.TabIndent)
+
+	fmt.Fprint(tw, "Task:")
+	fmt.Fprintf(tw, "\n  use\t%s", logger.Mask(task.Use))
+	fmt.Fprintf(tw, "\n  defined in\t%s", r.location(task))
+
+	explainAttribute(tw, "short", task.Short)
+	explainAttribute(tw, "shell", task.Shell)
+	explainAttribute(tw, "output", task.Output)
+	explainAttribute(tw, "prompt", task.Prompt)
+	if task.Private {
+		explainAttribute(tw, "private", "true")
+	}
+
+	if task.TTY {
+		explainAttribute(tw, "tty", "true")
+	}
+
+	if task.Lock.enabled && task.Lock.name != "" {
+		explainAttribute(tw, "lock", task.Lock.name)
+	} else if task.Lock.enabled {
+		explainAttribute(tw, "lock", task.Use)
+	}
+
+	for _, input := range task.Inputs {
+		explainAttribute(tw, "input", input.Name)
+	}
+
+	fmt.Fprint(tw, "\n\nCommands:")
+	for index, cmd := range task.Run {
+		if tasks := r.interpret(cmd); tasks != nil {
+			fmt.Fprintf(tw, "\n  %d.\truns task(s) %s", index+1, logger.Mask(strings.Join(tasks, ", ")))
+		} else {
+			fmt.Fprintf(tw, "\n  %d.\t%s", index+1, logger.Mask(cmd))
+		}
+	}
+
+	fmt.Fprintln(tw, "")
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nExecution order:")
+	r.printTree(w, task.Use, "  ", "  ", make(map[string]bool))
+
+	return nil
+}
+
+// explainAttribute prints an attribute of a task if it is not empty, once its secrets have been masked.
+func explainAttribute(w io.Writer, name string, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "\n  %s\t%s", name, logger.Mask(value))
+	}
+}
+
+// findDefinition returns the line of the configuration file where the given task is defined or 0 if not found.
+func (r *OrbitRunner) findDefinition(task *orbitTask) int {
+	file, err := os.Open(r.context.TemplateFilePath)
+	if err != nil {
+		return 0
+	}
+
+	defer file.Close()
+
+	pattern := regexp.MustCompile(`^\s*(-\s+)?use:\s*["']?` + regexp.QuoteMeta(task.Use) + `["']?\s*$`)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if pattern.MatchString(scanner.Text()) {
+			return line
+		}
+	}
+
+	return 0
+}
+
+// calls returns the names of the tasks called by the given task, in order of execution.
+func (r *OrbitRunner) calls(task *orbitTask) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, cmd := range task.Run {
+		for _, name := range r.interpret(cmd) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+/*
+selectTasks renders and returns the given tasks and the tasks they call, or all the tasks if no name given.
+In the latter case, the tasks which cannot be rendered are skipped.
+*/
+func (r *OrbitRunner) selectTasks(ctx gocontext.Context, names []string) ([]*orbitTask, error) {
+	if len(names) == 0 {
+		var tasks []*orbitTask
+		for _, task := range r.config.Tasks {
+			if err := r.renderTask(ctx, task); err != nil {
+				if ctx.Err() != nil {
+					return nil, err
+				}
+
+				logger.Infof("skipping task %s. Details:\n%s", task.Use, err)
+				continue
+			}
+
+			tasks = append(tasks, task)
+		}
+
+		return tasks, nil
+	}
+
+	for _, name := range names {
+		if r.getTask(name) == nil {
+			return nil, OrbitError.NewOrbitErrorf("task %s does not exist in configuration file %s", name, r.context.TemplateFilePath)
+		}
+	}
+
+	var (
+		tasks []*orbitTask
+		queue = names
+		seen  = make(map[string]bool)
+	)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		task := r.getTask(name)
+		if task == nil {
+			// a missing called task is displayed as such in the graph.
+			continue
+		}
+
+		if err := r.renderTask(ctx, task); err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, task)
+		queue = append(queue, r.calls(task)...)
+	}
+
+	return tasks, nil
+}
+
+// roots returns the tasks which are not called by others tasks, or the given tasks if any.
+func (r *OrbitRunner) roots(tasks []*orbitTask, names []string) []*orbitTask {
+	if len(names) > 0 {
+		roots := make([]*orbitTask, len(names))
+		for index, name := range names {
+			roots[index] = r.getTask(name)
+		}
+
+		return roots
+	}
+
+	called := make(map[string]bool)
+	for _, task := range tasks {
+		for _, name := range r.calls(task) {
+			if name != task.Use {
+				called[name] = true
+			}
+		}
+	}
+
+	var roots []*orbitTask
+	for _, task := range tasks {
+		if !called[task.Use] {
+			roots = append(roots, task)
+		}
+	}
+
+	// every task is called by another one, so there are only cycles.
+	if len(roots) == 0 {
+		return tasks
+	}
+
+	return roots
+}
+
+// printTree prints the given task and the tasks it calls as an ASCII tree.
+func (r *OrbitRunner) printTree(w io.Writer, name string, prefix string, childPrefix string, path map[string]bool) {
+	task := r.getTask(name)
+
+	switch {
+	case task == nil:
+		fmt.Fprintf(w, "%s%s (missing)\n", prefix, logger.Mask(name))
+		return
+	case path[name]:
+		fmt.Fprintf(w, "%s%s (cycle)\n", prefix, logger.Mask(name))
+		return
+	case r.generator != nil && !task.rendered:
+		fmt.Fprintf(w, "%s%s (not rendered)\n", prefix, logger.Mask(name))
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s\n", prefix, logger.Mask(name))
+
+	path[name] = true
+	defer delete(path, name)
+
+	calls := r.calls(task)
+	for index, call := range calls {
+		if index == len(calls)-1 {
+			r.printTree(w, call, childPrefix+"└── ", childPrefix+"    ", path)
+		} else {
+			r.printTree(w, call, childPrefix+"├── ", childPrefix+"│   ", path)
+		}
+	}
+}
+
+// printDOT prints the given tasks as a Graphviz DOT graph.
+func (r *OrbitRunner) printDOT(w io.Writer, tasks []*orbitTask) {
+	fmt.Fprintln(w, "digraph orbit {")
+
+	for _, task := range tasks {
+		fmt.Fprintf(w, "  %q;\n", logger.Mask(task.Use))
+	}
+
+	for _, task := range tasks {
+		for _, name := range r.calls(task) {
+			if r.getTask(name) == nil {
+				fmt.Fprintf(w, "  %q [style=dashed];\n", logger.Mask(name))
+			}
+
+			fmt.Fprintf(w, "  %q -> %q;\n", logger.Mask(task.Use), logger.Mask(name))
+		}
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
+// printMermaid prints the given tasks as a Mermaid flowchart.
+func (r *OrbitRunner) printMermaid(w io.Writer, tasks []*orbitTask) {
+	fmt.Fprintln(w, "graph TD")
+
+	// the names of the tasks may contain spaces, so each node has an identifier.
+	ids := make(map[string]string)
+	id := func(name string) string {
+		if _, ok := ids[name]; !ok {
+			ids[name] = fmt.Sprintf("task%d", len(ids))
+			fmt.Fprintf(w, "  %s[%q]\n", ids[name], logger.Mask(name))
+		}
+
+		return ids[name]
+	}
+
+	for _, task := range tasks {
+		id(task.Use)
+	}
+
+	for _, task := range tasks {
+		for _, name := range r.calls(task) {
+			fmt.Fprintf(w, "  %s --> %s\n", id(task.Use), id(name))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+const (
+	// runningStatus is the status of a task which is about to run.
+	runningStatus = "running"
+
+	// successStatus is the status of a task which has succeeded.
+	successStatus = "success"
+
+	// failureStatus is the status of a task which has failed.
+	failureStatus = "failure"
+
+	// finalHooksTimeout is the maximum duration of the on_failure, after_each and after_all hooks.
+	finalHooksTimeout = 30 * time.Second
+)
+
+/*
+runWithHooks runs the given task between the before_each and after_each hooks.
+
+If the task or one of its before_each hooks fails, the on_failure hooks are executed. Like the after_each
+hooks, they are executed even if the given context is done (see runFinalHooks).
+*/
+func (r *OrbitRunner) runWithHooks(ctx gocontext.Context, task *orbitTask) error {
+	if err := r.runHooks(ctx, "before_each", r.config.BeforeEach, hookEnv(task.Use, runningStatus, 0, nil)); err != nil {
+		r.runFailureHooks(hookEnv(task.Use, failureStatus, 0, err))
+		return err
+	}
+
+	start := time.Now()
+	err := r.run(ctx, task)
+	env := hookEnv(task.Use, hookStatus(err), time.Since(start), err)
+
+	if err != nil {
+		r.runFailureHooks(env)
+	}
+
+	return hookResult(err, r.runFinalHooks("after_each", r.config.AfterEach, env))
+}
+
+// runAllWithHooks runs the given tasks between the before_all and after_all hooks.
+func (r *OrbitRunner) runAllWithHooks(ctx gocontext.Context, tasks []*orbitTask) error {
+	names := make([]string, len(tasks))
+	for index, task := range tasks {
+		names[index] = task.Use
+	}
+
+	name := strings.Join(names, ",")
+	if err := r.runHooks(ctx, "before_all", r.config.BeforeAll, hookEnv(name, runningStatus, 0, nil)); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := r.runAll(ctx, tasks)
+	env := hookEnv(name, hookStatus(err), time.Since(start), err)
+
+	return hookResult(err, r.runFinalHooks("after_all", r.config.AfterAll, env))
+}
+
+// runFailureHooks executes the on_failure hooks. As a task has already failed, their errors are only logged.
+func (r *OrbitRunner) runFailureHooks(env []string) {
+	if err := r.runFinalHooks("on_failure", r.config.OnFailure, env); err != nil {
+		logger.Error(err)
+	}
+}
+
+/*
+runFinalHooks executes the given hooks, which run after a task, with their own context.
+
+Indeed, the context of the task may be done (e.g. because of a timeout or an interrupt), while these hooks
+should still report its failure. They are killed if they last more than finalHooksTimeout.
+*/
+func (r *OrbitRunner) runFinalHooks(kind string, hooks []string, env []string) error {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), finalHooksTimeout)
+	defer cancel()
+
+	return r.runHooks(ctx, kind, hooks, env)
+}
+
+// hookStatus returns the status of a task according to its error.
+func hookStatus(err error) string {
+	if err != nil {
+		return failureStatus
+	}
+
+	return successStatus
+}
+
+// hookResult returns the error of the task or, if nil, the error of its hooks.
+func hookResult(err error, hookErr error) error {
+	if err == nil {
+		return hookErr
+	}
+
+	if hookErr != nil {
+		logger.Error(hookErr)
+	}
+
+	return err
+}
+
+// runHooks executes the given hooks with the given environment variables, masking the secrets of their output.
+func (r *OrbitRunner) runHooks(ctx gocontext.Context, kind string, hooks []string, env []string) (err error) {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	output := r.newHookOutput()
+	defer func() {
+		if flushErr := output.flush(); flushErr != nil && err == nil {
+			err = OrbitError.NewOrbitErrorf("unable to flush the output of the %s hooks. Details:\n%s", kind, flushErr)
+		}
+	}()
+
+	for _, hook := range hooks {
+		e := r.prepareCommand(ctx, hook, &orbitTask{}, env)
+		e.Stdout = output.stdout
+		e.Stderr = output.stderr
+
+		logger.Infof("executing %s hook %s", kind, e.Args)
+
+		if err := r.execute(ctx, e, false); err != nil {
+			return OrbitError.NewOrbitErrorf("%s hook %s has failed. Details:\n%s", kind, logger.Mask(hook), err)
+		}
+	}
+
+	return nil
+}
+
+// hookEnv returns the environment variables describing a task to the hooks.
+func hookEnv(name string, status string, duration time.Duration, err error) []string {
+	env := []string{
+		"ORBIT_TASK=" + name,
+		"ORBIT_TASK_STATUS=" + status,
+		fmt.Sprintf("ORBIT_TASK_DURATION=%.3f", duration.Seconds()),
+	}
+
+	if err != nil {
+		env = append(env, "ORBIT_TASK_ERROR="+logger.Mask(err.Error()))
+	}
+
+	return env
+}
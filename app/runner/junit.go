@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+// maxJUnitStderr is the maximum size of the captured Stderr of a command: only its end is kept.
+const maxJUnitStderr = 64 * 1024
+
+type (
+	// orbitJUnitReport represents the root element of a JUnit XML report.
+	orbitJUnitReport struct {
+		XMLName xml.Name `xml:"testsuites"`
+
+		// Suites array contains a test suite per executed task.
+		Suites []*orbitJUnitTestSuite `xml:"testsuite"`
+	}
+
+	// orbitJUnitTestSuite represents an executed task.
+	orbitJUnitTestSuite struct {
+		// Name is the name of the task.
+		Name string `xml:"name,attr"`
+
+		// Tests is the number of commands of the task.
+		Tests int `xml:"tests,attr"`
+
+		// Failures is the number of failed commands.
+		Failures int `xml:"failures,attr"`
+
+		// Skipped is the number of commands which have not been executed.
+		Skipped int `xml:"skipped,attr"`
+
+		// Time is the duration of the task in seconds.
+		Time string `xml:"time,attr"`
+
+		// Timestamp is the date on which the task started (RFC 3339).
+		Timestamp string `xml:"timestamp,attr"`
+
+		// Cases array contains a test case per command of the task.
+		Cases []*orbitJUnitTestCase `xml:"testcase"`
+	}
+
+	// orbitJUnitTestCase represents a command of a task.
+	orbitJUnitTestCase struct {
+		// Name is the command.
+		Name string `xml:"name,attr"`
+
+		// ClassName is the name of the task.
+		ClassName string `xml:"classname,attr"`
+
+		// Time is the duration of the command in seconds.
+		Time string `xml:"time,attr"`
+
+		// Failure is set if the command has failed.
+		Failure *orbitJUnitFailure `xml:"failure,omitempty"`
+
+		// Skipped is set if the command has not been executed.
+		Skipped *orbitJUnitSkipped `xml:"skipped,omitempty"`
+
+		// SystemErr contains the captured Stderr of a failed command.
+		SystemErr string `xml:"system-err,omitempty"`
+	}
+
+	// orbitJUnitFailure gives details about a failed command, whose Stderr is in the system-err of its test case.
+	orbitJUnitFailure struct {
+		// Message is the error returned by the command (e.g. its exit status).
+		Message string `xml:"message,attr"`
+	}
+
+	// orbitJUnitSkipped gives details about a command which has not been executed.
+	orbitJUnitSkipped struct {
+		// Message is the reason why the command has been skipped.
+		Message string `xml:"message,attr,omitempty"`
+	}
+
+	// orbitTailBuffer is a writer which only keeps the last bytes written into it.
+	orbitTailBuffer struct {
+		// max is the maximum number of bytes kept.
+		max int
+
+		// data contains the last bytes written.
+		data []byte
+
+		// truncated is true if some bytes have been dropped.
+		truncated bool
+	}
+)
+
+// newJUnitTestSuite creates an instance of orbitJUnitTestSuite and appends it to the report.
+func (rep *orbitJUnitReport) newJUnitTestSuite(name string, start time.Time) *orbitJUnitTestSuite {
+	s := &orbitJUnitTestSuite{
+		Name:      name,
+		Timestamp: start.Format(time.RFC3339),
+	}
+
+	rep.Suites = append(rep.Suites, s)
+
+	return s
+}
+
+// passed adds a successful command to the test suite.
+func (s *orbitJUnitTestSuite) passed(cmd string, duration time.Duration) {
+	s.add(&orbitJUnitTestCase{
+		Name: cmd,
+		Time: formatJUnitDuration(duration),
+	})
+}
+
+// failed adds a failed command to the test suite.
+func (s *orbitJUnitTestSuite) failed(cmd string, duration time.Duration, err error, stderr string) {
+	s.Failures++
+	s.add(&orbitJUnitTestCase{
+		Name:      cmd,
+		Time:      formatJUnitDuration(duration),
+		Failure:   &orbitJUnitFailure{Message: err.Error()},
+		SystemErr: stderr,
+	})
+}
+
+// skipped adds a command which has not been executed to the test suite.
+func (s *orbitJUnitTestSuite) skipped(cmd string, reason string) {
+	s.Skipped++
+	s.add(&orbitJUnitTestCase{
+		Name:    cmd,
+		Time:    formatJUnitDuration(0),
+		Skipped: &orbitJUnitSkipped{Message: reason},
+	})
+}
+
+// add appends a test case to the test suite once its secrets have been masked.
+func (s *orbitJUnitTestSuite) add(c *orbitJUnitTestCase) {
+	c.Name = logger.Mask(c.Name)
+	c.SystemErr = logger.Mask(c.SystemErr)
+	if c.Failure != nil {
+		c.Failure.Message = logger.Mask(c.Failure.Message)
+	}
+
+	c.ClassName = s.Name
+	s.Tests++
+	s.Cases = append(s.Cases, c)
+}
+
+// done sets the duration of the test suite.
+func (s *orbitJUnitTestSuite) done(duration time.Duration) {
+	s.Time = formatJUnitDuration(duration)
+}
+
+// newTailBuffer creates an instance of orbitTailBuffer which keeps the given number of bytes.
+func newTailBuffer(max int) *orbitTailBuffer {
+	return &orbitTailBuffer{max: max}
+}
+
+// Write appends the given bytes, dropping the oldest ones beyond the maximum size.
+func (b *orbitTailBuffer) Write(p []byte) (int, error) {
+	if len(p) >= b.max {
+		b.truncated = b.truncated || len(b.data) > 0 || len(p) > b.max
+		b.data = append(b.data[:0], p[len(p)-b.max:]...)
+		return len(p), nil
+	}
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = append(b.data[:0], b.data[len(b.data)-b.max:]...)
+		b.truncated = true
+	}
+
+	return len(p), nil
+}
+
+// String returns the kept bytes, starting with a notice if some bytes have been dropped.
+func (b *orbitTailBuffer) String() string {
+	if b.truncated {
+		return "[...]\n" + string(b.data)
+	}
+
+	return string(b.data)
+}
+
+// formatJUnitDuration returns a duration in seconds as expected by JUnit.
+func formatJUnitDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+/*
+WriteJUnitReport writes a JUnit XML report of the executed tasks into a file.
+
+Each executed task is a test suite and each of its commands a test case.
+This function should be called after Run function, even if it has failed.
+*/
+func (r *OrbitRunner) WriteJUnitReport(outputPath string) error {
+	data, err := xml.MarshalIndent(r.report, "", "  ")
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to encode the JUnit report. Details:\n%s", err)
+	}
+
+	data = append([]byte(xml.Header), data...)
+	if err := ioutil.WriteFile(outputPath, data, 0644); err != nil {
+		return OrbitError.NewOrbitErrorf("unable to write the JUnit report %s. Details:\n%s", outputPath, err)
+	}
+
+	logger.Infof("JUnit report %s has been created", outputPath)
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	gocontext "context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+// default directory of the lock files.
+const defaultLockDir = ".orbit/locks"
+
+// delay between two attempts to acquire a lock.
+const lockRetryDelay = 100 * time.Millisecond
+
+type (
+	// orbitLock represents the lock option of a task. It may be a boolean
+	// (the lock is named after the task) or the name of a lock shared by many tasks.
+	orbitLock struct {
+		// enabled is true if the task has to acquire a lock.
+		enabled bool
+
+		// name is the optional name of the lock.
+		name string
+	}
+
+	// orbitLockFile is an acquired lock.
+	orbitLockFile struct {
+		// file is the lock file.
+		file *os.File
+
+		// path is the path of the lock file.
+		path string
+
+		// runner is the instance of OrbitRunner holding the lock.
+		runner *OrbitRunner
+	}
+)
+
+// UnmarshalYAML populates an orbitLock from a boolean or a string.
+func (l *orbitLock) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		l.enabled = enabled
+		return nil
+	}
+
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+
+	l.enabled = name != ""
+	l.name = name
+
+	return nil
+}
+
+// SetLockTimeout sets how long a task waits for a lock held by another process.
+func (r *OrbitRunner) SetLockTimeout(timeout time.Duration) {
+	r.lockTimeout = timeout
+}
+
+/*
+acquireLock takes the advisory lock of the given task.
+
+If the lock is held by another process, retries until the lock timeout
+is reached. Returns nil if the lock is already held by the current runner
+(e.g. nested tasks sharing the same lock).
+*/
+func (r *OrbitRunner) acquireLock(ctx gocontext.Context, task *orbitTask) (*orbitLockFile, error) {
+	name := task.Lock.name
+	if name == "" {
+		name = task.Use
+	}
+
+	lockDir := filepath.Join(r.dir, defaultLockDir)
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to create the lock directory %s. Details:\n%s", lockDir, err)
+	}
+
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	lockFilePath := filepath.Join(lockDir, name+".lock")
+
+	if r.locks[lockFilePath] {
+		logger.Debugf("lock %s is already held by the current process", lockFilePath)
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(lockFilePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to open the lock file %s. Details:\n%s", lockFilePath, err)
+	}
+
+	deadline := time.Now().Add(r.lockTimeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, OrbitError.NewOrbitErrorf("unable to acquire the lock %s. Details:\n%s", lockFilePath, err)
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+
+			holder, _ := ioutil.ReadFile(lockFilePath)
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				return nil, OrbitError.NewOrbitErrorf("task %s is locked by process %s (lock file %s)", task.Use, pid, lockFilePath)
+			}
+
+			return nil, OrbitError.NewOrbitErrorf("task %s is locked by another process (lock file %s)", task.Use, lockFilePath)
+		}
+
+		logger.Debugf("lock %s is held by another process, retrying", lockFilePath)
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, OrbitError.NewOrbitErrorf("task %s has been cancelled while waiting for the lock %s. Details:\n%s", task.Use, lockFilePath, ctx.Err())
+		case <-time.After(lockRetryDelay):
+		}
+	}
+
+	// writes the PID of the current process so that others know who is holding the lock.
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	}
+
+	logger.Infof("lock %s has been acquired by task %s", lockFilePath, task.Use)
+	r.locks[lockFilePath] = true
+
+	return &orbitLockFile{file: file, path: lockFilePath, runner: r}, nil
+}
+
+// release releases the lock.
+func (l *orbitLockFile) release() error {
+	defer l.file.Close()
+	delete(l.runner.locks, l.path)
+
+	// the file is not removed, otherwise another process may lock a file which is about to disappear.
+	l.file.Truncate(0)
+
+	return unlockFile(l.file)
+}
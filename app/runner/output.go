@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+)
+
+const (
+	// interleavedOutput streams the output of the commands as is (default).
+	interleavedOutput = "interleaved"
+
+	// prefixedOutput streams the output of the commands with the name of the task at the beginning of each line.
+	prefixedOutput = "prefixed"
+
+	// groupedOutput prints the output of the commands once the task is done.
+	groupedOutput = "grouped"
+
+	// fileOutput only writes the output of the commands into the log file of the task.
+	fileOutput = "file"
+)
+
+// default directory of the log files if none given and a task uses the file output.
+const defaultLogDir = ".orbit/logs"
+
+type (
+	// orbitTaskOutput provides the writers used by the commands of a task.
+	orbitTaskOutput struct {
+		// stdout is the writer of the commands' Stdout.
+		stdout io.Writer
+
+		// stderr is the writer of the commands' Stderr.
+		stderr io.Writer
+
+		// flushers are called once the task is done.
+		flushers []func() error
+	}
+
+	// orbitLineWriter writes line by line after transforming each line.
+	orbitLineWriter struct {
+		// out is the underlying writer.
+		out io.Writer
+
+		// transform is applied to each line.
+		transform func(line string) string
+
+		// partial returns the part of the incomplete line which may be written right away
+		// once transformed, and the part to keep. If nil, the incomplete line is kept as is.
+		partial func(line string) (string, string)
+
+		// terminate adds a line break to the remaining incomplete line when flushing.
+		terminate bool
+
+		// line contains the current incomplete line.
+		line bytes.Buffer
+	}
+
+	// orbitSyncWriter allows many goroutines to write into the same writer.
+	orbitSyncWriter struct {
+		// mutex is locked while writing.
+		mutex sync.Mutex
+
+		// out is the underlying writer.
+		out io.Writer
+	}
+)
+
+// newTaskOutput creates an instance of orbitTaskOutput according to the output mode of the given task.
+func (r *OrbitRunner) newTaskOutput(task *orbitTask) (*orbitTaskOutput, error) {
+	o := &orbitTaskOutput{}
+
+	switch task.Output {
+	case "", interleavedOutput:
+		o.stdout = r.stdout
+		o.stderr = r.stderr
+	case prefixedOutput:
+		stdout := newPrefixWriter(r.stdout, "["+task.Use+"] ")
+		stderr := newPrefixWriter(r.stderr, "["+task.Use+"] ")
+		o.stdout = stdout
+		o.stderr = stderr
+		o.flushers = append(o.flushers, stdout.flush, stderr.flush)
+	case groupedOutput:
+		// the streams are kept separate, so that the Stderr of the task is still printed to Stderr.
+		var stdout, stderr bytes.Buffer
+		o.stdout = &orbitSyncWriter{out: &stdout}
+		o.stderr = &orbitSyncWriter{out: &stderr}
+		o.flushers = append(o.flushers, func() error {
+			_, err := stdout.WriteTo(r.stdout)
+			return err
+		}, func() error {
+			_, err := stderr.WriteTo(r.stderr)
+			return err
+		})
+	case fileOutput:
+		o.stdout = ioutil.Discard
+		o.stderr = ioutil.Discard
+	default:
+		return nil, OrbitError.NewOrbitErrorf("task %s has an unknown output %s. Available outputs are %s, %s, %s and %s", task.Use, task.Output, interleavedOutput, prefixedOutput, groupedOutput, fileOutput)
+	}
+
+	// like the working directory of the commands, a relative log directory is relative to the one of the runner.
+	logDir := r.logDir
+	if logDir == "" && task.Output == fileOutput {
+		logDir = defaultLogDir
+	}
+
+	if logDir != "" && !filepath.IsAbs(logDir) {
+		logDir = filepath.Join(r.dir, logDir)
+	}
+
+	if logDir != "" {
+		// the output of the commands is also written into the log file of the task.
+		file, err := r.openLogFile(logDir, task)
+		if err != nil {
+			return nil, err
+		}
+
+		log := &orbitSyncWriter{out: file}
+		o.stdout = io.MultiWriter(o.stdout, log)
+		o.stderr = io.MultiWriter(o.stderr, log)
+		o.flushers = append(o.flushers, file.Close)
+	}
+
+	o.mask()
+
+	return o, nil
+}
+
+// newHookOutput creates an instance of orbitTaskOutput which streams the output of the hooks as is.
+func (r *OrbitRunner) newHookOutput() *orbitTaskOutput {
+	o := &orbitTaskOutput{stdout: r.stdout, stderr: r.stderr}
+	o.mask()
+
+	return o
+}
+
+// mask masks the secrets written into the writers of the output, if any.
+func (o *orbitTaskOutput) mask() {
+	if !logger.HasSecrets() {
+		return
+	}
+
+	// the secrets are masked before reaching any writer, so they must be flushed first.
+	stdout := newMaskWriter(o.stdout)
+	stderr := newMaskWriter(o.stderr)
+	o.stdout = stdout
+	o.stderr = stderr
+	o.flushers = append([]func() error{stdout.flush, stderr.flush}, o.flushers...)
+}
+
+/*
+openLogFile opens the log file of the given task.
+
+The log file is truncated the first time the task is run
+and appended the next times.
+*/
+func (r *OrbitRunner) openLogFile(logDir string, task *orbitTask) (*os.File, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to create the log directory %s. Details:\n%s", logDir, err)
+	}
+
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(task.Use)
+	logFilePath := filepath.Join(logDir, name+".log")
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !r.logged[logFilePath] {
+		flag |= os.O_TRUNC
+		r.logged[logFilePath] = true
+	}
+
+	file, err := os.OpenFile(logFilePath, flag, 0644)
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to open the log file %s. Details:\n%s", logFilePath, err)
+	}
+
+	return file, nil
+}
+
+// flush calls the flushers of the task output.
+func (o *orbitTaskOutput) flush() error {
+	var result error
+	for _, flusher := range o.flushers {
+		if err := flusher(); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// newPrefixWriter creates an instance of orbitLineWriter which writes a prefix at the beginning of each line.
+func newPrefixWriter(out io.Writer, prefix string) *orbitLineWriter {
+	return &orbitLineWriter{
+		out: out,
+		transform: func(line string) string {
+			return prefix + line
+		},
+		terminate: true,
+	}
+}
+
+// newMaskWriter creates an instance of orbitLineWriter which masks the secrets of each line.
+func newMaskWriter(out io.Writer) *orbitLineWriter {
+	// the incomplete lines (e.g. the prompt of "read -p") are not held back, except a possible beginning of a secret.
+	return &orbitLineWriter{
+		out:       out,
+		transform: logger.Mask,
+		partial:   logger.MaskPartial,
+	}
+}
+
+// Write writes the complete lines once transformed and keeps the incomplete line for later.
+func (w *orbitLineWriter) Write(p []byte) (int, error) {
+	w.line.Write(p)
+
+	for {
+		index := bytes.IndexByte(w.line.Bytes(), '\n')
+		if index < 0 {
+			break
+		}
+
+		if _, err := io.WriteString(w.out, w.transform(string(w.line.Next(index+1)))); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.partial != nil && w.line.Len() > 0 {
+		ready, rest := w.partial(w.line.String())
+		w.line.Reset()
+		w.line.WriteString(rest)
+
+		if _, err := io.WriteString(w.out, ready); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// flush writes the remaining incomplete line once transformed.
+func (w *orbitLineWriter) flush() error {
+	if w.line.Len() == 0 {
+		return nil
+	}
+
+	line := w.line.String()
+	if w.terminate {
+		line += "\n"
+	}
+
+	_, err := io.WriteString(w.out, w.transform(line))
+	w.line.Reset()
+
+	return err
+}
+
+// Write writes into the underlying writer while holding the mutex.
+func (w *orbitSyncWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.out.Write(p)
+}
+
+// SetLogDir sets the directory where the output of each task is written into a file named after the task.
+func (r *OrbitRunner) SetLogDir(logDir string) {
+	r.logDir = logDir
+}
@@ -0,0 +1,71 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// setProcessGroup starts the given command in its own process group,
+// so that its child processes may be killed with it.
+func setProcessGroup(e *exec.Cmd) {
+	e.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+/*
+setForegroundProcessGroup starts the given command in its own process group, which becomes
+the foreground process group of the given terminal if Orbit is in the foreground: the command
+may then read from the terminal, which sends an interrupt to each process of this group.
+
+Returns false if the process group of the command does not become the foreground one.
+*/
+func setForegroundProcessGroup(e *exec.Cmd, tty int) bool {
+	pgrp, err := foregroundProcessGroup(tty)
+	if err != nil || pgrp != syscall.Getpgrp() {
+		setProcessGroup(e)
+		return false
+	}
+
+	e.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: tty}
+
+	return true
+}
+
+// restoreForegroundProcessGroup puts the process group of Orbit back in the foreground of the given terminal.
+func restoreForegroundProcessGroup(tty int) error {
+	// Orbit is in the background until then, so changing the foreground process group would stop it.
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	pgrp := int32(syscall.Getpgrp())
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(tty), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// foregroundProcessGroup returns the foreground process group of the given terminal.
+func foregroundProcessGroup(tty int) (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(tty), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+
+	return int(pgrp), nil
+}
+
+// killProcessTree kills the given process and its child processes.
+func killProcessTree(process *os.Process) error {
+	if err := syscall.Kill(-process.Pid, syscall.SIGKILL); err == nil {
+		return nil
+	}
+
+	// the process is not the leader of its process group.
+	return process.Kill()
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup does nothing on Windows as taskkill finds the child processes by itself.
+func setProcessGroup(e *exec.Cmd) {}
+
+// setForegroundProcessGroup does nothing on Windows as there are no process groups to put in the foreground.
+func setForegroundProcessGroup(e *exec.Cmd, tty int) bool {
+	return false
+}
+
+// restoreForegroundProcessGroup does nothing on Windows.
+func restoreForegroundProcessGroup(tty int) error {
+	return nil
+}
+
+// killProcessTree kills the given process and its child processes.
+func killProcessTree(process *os.Process) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid)).Run(); err == nil {
+		return nil
+	}
+
+	return process.Kill()
+}
@@ -0,0 +1,221 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/logger"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type (
+	// orbitInput represents a value asked to the user before running a task.
+	orbitInput struct {
+		// Name is the name of the environment variable which will contain the value.
+		Name string `yaml:"name"`
+
+		// Prompt is the question displayed to the user.
+		Prompt string `yaml:"prompt,omitempty"`
+
+		// Default is the value used if the user gives an empty answer.
+		Default string `yaml:"default,omitempty"`
+
+		// Choices array restricts the values the user may give.
+		Choices []string `yaml:"choices,omitempty"`
+
+		// Hidden allows to hide the value typed by the user (e.g. passwords).
+		Hidden bool `yaml:"hidden,omitempty"`
+	}
+
+	// orbitPrompter asks questions to the user.
+	orbitPrompter struct {
+		// assumeYes automatically answers the questions.
+		assumeYes bool
+
+		// interactive is true if the user is able to answer the questions.
+		interactive bool
+
+		// src is the underlying reader of the answers, from which a hidden answer
+		// is read without echo if it is a terminal.
+		src io.Reader
+
+		// in is the reader of the answers.
+		in *bufio.Reader
+
+		// out is the writer of the questions.
+		out io.Writer
+	}
+)
+
+// newPrompter creates an instance of orbitPrompter reading from Stdin.
+func newPrompter(assumeYes bool) *orbitPrompter {
+	return &orbitPrompter{
+		assumeYes:   assumeYes,
+		interactive: terminal.IsTerminal(int(os.Stdin.Fd())),
+		src:         os.Stdin,
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stderr,
+	}
+}
+
+/*
+SetAssumeYes allows to automatically answer the prompts of the tasks:
+confirmations are accepted and inputs take their default value.
+*/
+func (r *OrbitRunner) SetAssumeYes(assumeYes bool) {
+	r.prompter.assumeYes = assumeYes
+}
+
+/*
+prompt asks the confirmation and the inputs of the given task.
+
+Returns the inputs as environment variables. They are also kept as the answers
+of the task, so that its attributes may use them once rendered.
+*/
+func (p *orbitPrompter) prompt(task *orbitTask) ([]string, error) {
+	if task.Prompt != "" {
+		if err := p.confirm(task); err != nil {
+			return nil, err
+		}
+	}
+
+	var env []string
+	answers := make(map[string]interface{})
+	for _, input := range task.Inputs {
+		// the value may have already been given by the user.
+		value, ok := os.LookupEnv(input.Name)
+		if ok {
+			logger.Debugf("input %s of task %s has been given by an environment variable", input.Name, task.Use)
+		} else {
+			var err error
+			if value, err = p.ask(task, input); err != nil {
+				return nil, err
+			}
+		}
+
+		// a hidden value is masked wherever it comes from.
+		if input.Hidden {
+			logger.AddSecrets(value)
+		}
+
+		// the value may not come from the user, e.g. a default value or an environment variable.
+		if !input.accepts(value) {
+			return nil, OrbitError.NewOrbitErrorf("value of input %s of task %s is not one of %s", input.Name, task.Use, strings.Join(input.Choices, ", "))
+		}
+
+		env = append(env, input.Name+"="+value)
+		answers[input.Name] = value
+	}
+
+	task.answers = answers
+
+	return env, nil
+}
+
+// confirm asks the user to confirm the execution of the given task.
+func (p *orbitPrompter) confirm(task *orbitTask) error {
+	if p.assumeYes {
+		logger.Infof("task %s has been automatically confirmed", task.Use)
+		return nil
+	}
+
+	if !p.interactive {
+		return OrbitError.NewOrbitErrorf("task %s requires a confirmation but Stdin is not a terminal. Use the --yes flag to confirm it", task.Use)
+	}
+
+	fmt.Fprintf(p.out, "%s [y/N] ", task.Prompt)
+	answer, err := p.readLine(false)
+	if err != nil {
+		return OrbitError.NewOrbitErrorf("unable to read the confirmation of task %s. Details:\n%s", task.Use, err)
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return nil
+	}
+
+	return OrbitError.NewOrbitErrorf("task %s has been aborted", task.Use)
+}
+
+// ask asks the user the value of the given input.
+func (p *orbitPrompter) ask(task *orbitTask, input *orbitInput) (string, error) {
+	if p.assumeYes {
+		if input.Default == "" {
+			return "", OrbitError.NewOrbitErrorf("input %s of task %s has no default value", input.Name, task.Use)
+		}
+
+		return input.Default, nil
+	}
+
+	if !p.interactive {
+		return "", OrbitError.NewOrbitErrorf("input %s of task %s requires a value but Stdin is not a terminal", input.Name, task.Use)
+	}
+
+	question := input.Prompt
+	if question == "" {
+		question = input.Name
+	}
+
+	if len(input.Choices) > 0 {
+		question += " (" + strings.Join(input.Choices, "/") + ")"
+	}
+
+	if input.Default != "" {
+		question += " [" + input.Default + "]"
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s: ", question)
+		answer, err := p.readLine(input.Hidden)
+		if err != nil {
+			return "", OrbitError.NewOrbitErrorf("unable to read the input %s of task %s. Details:\n%s", input.Name, task.Use, err)
+		}
+
+		if answer == "" {
+			answer = input.Default
+		}
+
+		if answer != "" && input.accepts(answer) {
+			return answer, nil
+		}
+
+		fmt.Fprintln(p.out, "invalid value, please try again.")
+	}
+}
+
+// readLine reads an answer from the user, without echo if it is hidden and typed in a terminal.
+func (p *orbitPrompter) readLine(hidden bool) (string, error) {
+	if f, ok := p.src.(*os.File); ok && hidden && terminal.IsTerminal(int(f.Fd())) {
+		data, err := terminal.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(p.out, "")
+
+		return string(data), err
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// accepts returns true if the given value is one of the choices of the input.
+func (input *orbitInput) accepts(value string) bool {
+	if len(input.Choices) == 0 {
+		return true
+	}
+
+	for _, choice := range input.Choices {
+		if value == choice {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioctlGetTermios is the request retrieving the attributes of a terminal.
+	ioctlGetTermios = unix.TIOCGETA
+
+	// ioctlSetTermios is the request setting the attributes of a terminal.
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+// openPTY allocates a pseudo-terminal and returns its master and slave sides.
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// grants and unlocks the slave side...
+	for _, request := range []uint{unix.TIOCPTYGRANT, unix.TIOCPTYUNLK} {
+		request := request
+		if err := control(master, func(fd int) error { return unix.IoctlSetInt(fd, request, 0) }); err != nil {
+			master.Close()
+			return nil, nil, err
+		}
+	}
+
+	// ...then retrieves its name.
+	name := make([]byte, 128)
+	err = control(master, func(fd int) error {
+		if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name[0]))); errno != 0 {
+			return errno
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err := os.OpenFile(string(name[:bytes.IndexByte(name, 0)]), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
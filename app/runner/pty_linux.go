@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioctlGetTermios is the request retrieving the attributes of a terminal.
+	ioctlGetTermios = unix.TCGETS
+
+	// ioctlSetTermios is the request setting the attributes of a terminal.
+	ioctlSetTermios = unix.TCSETS
+)
+
+// openPTY allocates a pseudo-terminal and returns its master and slave sides.
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// unlocks the slave side...
+	var unlock int32
+	err = control(master, func(fd int) error {
+		if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+			return errno
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	// ...then retrieves its number.
+	var number int
+	err = control(master, func(fd int) (err error) {
+		number, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+		return err
+	})
+
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(number), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
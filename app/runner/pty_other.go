@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package runner
+
+import (
+	gocontext "context"
+	"os/exec"
+
+	"github.com/gulien/orbit/app/logger"
+)
+
+// executeInTerminal runs the given command without pseudo-terminal, as they are not supported on this platform.
+func (r *OrbitRunner) executeInTerminal(ctx gocontext.Context, e *exec.Cmd) error {
+	logger.Debugf("pseudo-terminals are not supported on this platform, running %s without it", e.Args)
+	return r.execute(ctx, e, true)
+}
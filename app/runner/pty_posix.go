@@ -0,0 +1,164 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package runner
+
+import (
+	gocontext "context"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gulien/orbit/app/logger"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxTerminalDrain is the maximum duration the output of the pseudo-terminal is still copied once the command has exited.
+const maxTerminalDrain = 500 * time.Millisecond
+
+/*
+executeInTerminal runs the given command inside a pseudo-terminal,
+so that it behaves as if it was attached to the terminal of the user
+(colors, progress bars...).
+
+The Stdout and the Stderr of the command are merged and copied
+to the Stdout of the command, which still may prefix or capture them:
+the Stderr of the command is therefore never captured, so the system-err
+of its JUnit test case is empty. If the pseudo-terminal cannot be allocated,
+the command runs without it.
+
+A background process started by the command may keep the pseudo-terminal
+open: its output is only copied until the context is done, or for
+maxTerminalDrain at most once the command has exited.
+*/
+func (r *OrbitRunner) executeInTerminal(ctx gocontext.Context, e *exec.Cmd) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		logger.Debugf("unable to allocate a pseudo-terminal, running %s without it. Details:\n%s", e.Args, err)
+		return r.execute(ctx, e, true)
+	}
+
+	defer master.Close()
+
+	// the lines keep their "\n" line endings, as with a pipe.
+	if err := disableOutputProcessing(slave); err != nil {
+		logger.Debugf("unable to configure the pseudo-terminal %s. Details:\n%s", slave.Name(), err)
+	}
+
+	stop := r.propagateWindowSize(master)
+	defer stop()
+
+	out := e.Stdout
+	e.Stdout = slave
+	e.Stderr = slave
+	e.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 1}
+
+	copied := make(chan struct{})
+	go func() {
+		// reading from the master returns an error once every process has closed the slave.
+		io.Copy(out, master)
+		close(copied)
+	}()
+
+	err = r.execute(ctx, e, false)
+	slave.Close()
+
+	select {
+	case <-copied:
+		return err
+	case <-ctx.Done():
+	case <-time.After(maxTerminalDrain):
+	}
+
+	// the master is read through the runtime poller, so a deadline interrupts the copy.
+	if deadlineErr := master.SetReadDeadline(time.Now()); deadlineErr != nil {
+		logger.Debugf("unable to stop reading the pseudo-terminal. Details:\n%s", deadlineErr)
+		return err
+	}
+
+	<-copied
+
+	return err
+}
+
+/*
+control calls the given function with the file descriptor of the given file.
+Unlike Fd, it does not put the file in blocking mode, so that the file still
+may be read through the runtime poller.
+*/
+func control(file *os.File, f func(fd int) error) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fErr error
+	if err := conn.Control(func(fd uintptr) { fErr = f(int(fd)) }); err != nil {
+		return err
+	}
+
+	return fErr
+}
+
+// disableOutputProcessing prevents the given pseudo-terminal from translating "\n" into "\r\n".
+func disableOutputProcessing(tty *os.File) error {
+	termios, err := unix.IoctlGetTermios(int(tty.Fd()), ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+
+	termios.Oflag &^= unix.ONLCR
+
+	return unix.IoctlSetTermios(int(tty.Fd()), ioctlSetTermios, termios)
+}
+
+/*
+propagateWindowSize copies the window size of the terminal of the user
+to the given pseudo-terminal, then again each time the window is resized.
+
+Returns a function which stops the propagation.
+*/
+func (r *OrbitRunner) propagateWindowSize(master *os.File) func() {
+	terminal, ok := r.stdout.(*os.File)
+	if !ok {
+		return func() {}
+	}
+
+	resize := func() {
+		size, err := unix.IoctlGetWinsize(int(terminal.Fd()), unix.TIOCGWINSZ)
+		if err != nil {
+			logger.Debugf("unable to retrieve the window size. Details:\n%s", err)
+			return
+		}
+
+		if err := control(master, func(fd int) error { return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, size) }); err != nil {
+			logger.Debugf("unable to set the window size of the pseudo-terminal. Details:\n%s", err)
+		}
+	}
+
+	resize()
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
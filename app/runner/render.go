@@ -0,0 +1,154 @@
+package runner
+
+import (
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/generator"
+	"github.com/gulien/orbit/app/logger"
+)
+
+/*
+render renders the hooks, the remote cache and the names of the tasks of the configuration file.
+
+The others attributes of a task are only rendered once the task is needed
+(see renderTask), so that a task may use data which are not available
+when running the others tasks.
+*/
+func (r *OrbitRunner) render(ctx gocontext.Context) error {
+	r.generator = generator.NewOrbitGenerator(r.context)
+	name := filepath.Base(r.context.TemplateFilePath)
+
+	data := context.MergeData(r.context.Payload, r.config.Vars, r.context.Overrides)
+	for _, hooks := range [][]string{r.config.BeforeAll, r.config.AfterAll, r.config.BeforeEach, r.config.AfterEach, r.config.OnFailure} {
+		for index, hook := range hooks {
+			value, err := r.generator.ExecuteText(ctx, name, hook, data)
+			if err != nil {
+				return OrbitError.NewOrbitErrorf("unable to render the hooks of configuration file %s. Details:\n%s", r.context.TemplateFilePath, err)
+			}
+
+			hooks[index] = value
+		}
+	}
+
+	if remote := r.config.RemoteCache; remote != nil {
+		for _, field := range []*string{&remote.URL, &remote.Mode} {
+			value, err := r.generator.ExecuteText(ctx, name, *field, data)
+			if err != nil {
+				return OrbitError.NewOrbitErrorf("unable to render the remote cache of configuration file %s. Details:\n%s", r.context.TemplateFilePath, err)
+			}
+
+			*field = value
+		}
+	}
+
+	for _, task := range r.config.Tasks {
+		use, err := r.renderText(ctx, task, task.Use)
+		if err != nil {
+			return OrbitError.NewOrbitErrorf("unable to render the name of task %s (%s). Details:\n%s", task.Use, r.location(task), err)
+		}
+
+		task.Use = use
+	}
+
+	return nil
+}
+
+/*
+renderTask renders the attributes of the given task, if not already done.
+
+The data applied to a task are, by order of precedence: the values given
+by the user, the inputs of the task, the variables of the task, the global
+variables and the payload. As the others attributes may use the inputs,
+only the prompt and the inputs of a task with inputs are rendered until
+the user has answered them (see orbitPrompter.prompt).
+*/
+func (r *OrbitRunner) renderTask(ctx gocontext.Context, task *orbitTask) error {
+	if r.generator == nil || task.rendered {
+		return nil
+	}
+
+	if !task.inputsRendered {
+		if err := r.renderFields(ctx, task, task.inputFields()); err != nil {
+			return err
+		}
+
+		task.inputsRendered = true
+	}
+
+	if len(task.Inputs) > 0 && task.answers == nil {
+		logger.Debugf("task %s will be rendered once its inputs have been given", task.Use)
+		return nil
+	}
+
+	if err := r.renderFields(ctx, task, task.fields()); err != nil {
+		return err
+	}
+
+	task.rendered = true
+	logger.Debugf("task %s has been rendered", task.Use)
+
+	return nil
+}
+
+// renderFields renders the given attributes of the given task.
+func (r *OrbitRunner) renderFields(ctx gocontext.Context, task *orbitTask, fields []*string) error {
+	for _, field := range fields {
+		value, err := r.renderText(ctx, task, *field)
+		if err != nil {
+			return OrbitError.NewOrbitErrorf("unable to render task %s (%s). Details:\n%s", task.Use, r.location(task), err)
+		}
+
+		*field = value
+	}
+
+	return nil
+}
+
+// renderText renders the given text with the data of the given task.
+func (r *OrbitRunner) renderText(ctx gocontext.Context, task *orbitTask, text string) (string, error) {
+	if r.generator == nil || task.rendered {
+		return text, nil
+	}
+
+	data := context.MergeData(r.context.Payload, r.config.Vars, task.Vars, task.answers, r.context.Overrides)
+
+	return r.generator.ExecuteText(ctx, filepath.Base(r.context.TemplateFilePath), text, data)
+}
+
+// location returns where the given task is defined in the configuration file.
+func (r *OrbitRunner) location(task *orbitTask) string {
+	if line := r.findDefinition(task); line > 0 {
+		return fmt.Sprintf("%s:%d", r.context.TemplateFilePath, line)
+	}
+
+	return r.context.TemplateFilePath
+}
+
+// fields returns the attributes of the task, except its name and the ones returned by inputFields, which may be data-driven templates.
+func (task *orbitTask) fields() []*string {
+	fields := []*string{&task.Shell, &task.Output, &task.Lock.name}
+	for _, values := range [][]string{task.Run, task.Sources, task.Generates, task.Env} {
+		for index := range values {
+			fields = append(fields, &values[index])
+		}
+	}
+
+	return fields
+}
+
+// inputFields returns the attributes of the task displayed to the user before running it, which may be data-driven templates.
+func (task *orbitTask) inputFields() []*string {
+	fields := []*string{&task.Short, &task.Prompt}
+	for _, input := range task.Inputs {
+		fields = append(fields, &input.Name, &input.Prompt, &input.Default)
+		for index := range input.Choices {
+			fields = append(fields, &input.Choices[index])
+		}
+	}
+
+	return fields
+}
@@ -5,24 +5,31 @@ in a configuration file (by default "orbit.yml").
 These tasks executes one ore more commands one by one.
 
 Thanks to the generator package, the configuration file may be a data-driven template which is executed at runtime
-(e.g. no file generated).
+(e.g. no file generated). If the configuration file is a valid YAML file before being executed, each task is
+rendered with its own variables; otherwise the configuration file is executed as a whole.
 */
 package runner
 
 import (
+	"bufio"
+	gocontext "context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/gulien/orbit/app/context"
 	OrbitError "github.com/gulien/orbit/app/error"
 	"github.com/gulien/orbit/app/generator"
 	"github.com/gulien/orbit/app/logger"
 
+	"golang.org/x/crypto/ssh/terminal"
 	"gopkg.in/yaml.v2"
 )
 
@@ -34,6 +41,28 @@ type (
 	orbitRunnerConfig struct {
 		// Tasks array represents the tasks defined in the configuration file.
 		Tasks []*orbitTask `yaml:"tasks"`
+
+		// Vars map contains the variables merged over the payload
+		// when rendering the tasks.
+		Vars map[string]interface{} `yaml:"vars,omitempty"`
+
+		// BeforeAll is the stack of commands executed before running the given tasks.
+		BeforeAll []string `yaml:"before_all,omitempty"`
+
+		// AfterAll is the stack of commands executed after running the given tasks.
+		AfterAll []string `yaml:"after_all,omitempty"`
+
+		// BeforeEach is the stack of commands executed before running each task.
+		BeforeEach []string `yaml:"before_each,omitempty"`
+
+		// AfterEach is the stack of commands executed after running each task.
+		AfterEach []string `yaml:"after_each,omitempty"`
+
+		// OnFailure is the stack of commands executed when a task has failed.
+		OnFailure []string `yaml:"on_failure,omitempty"`
+
+		// RemoteCache is the remote cache of the files generated by the tasks.
+		RemoteCache *orbitRemoteCacheConfig `yaml:"remote_cache,omitempty"`
 	}
 
 	// orbitTask represents a task as defined in the configuration file.
@@ -52,8 +81,56 @@ type (
 		// printing the available tasks.
 		Private bool `yaml:"private,omitempty"`
 
+		// Prompt is a question the user has to confirm
+		// before running the task.
+		Prompt string `yaml:"prompt,omitempty"`
+
+		// Inputs array contains the values asked to the user
+		// before running the task.
+		Inputs []*orbitInput `yaml:"inputs,omitempty"`
+
+		// Vars map contains the variables merged over the payload
+		// and the global variables when rendering the task.
+		Vars map[string]interface{} `yaml:"vars,omitempty"`
+
+		// Lock allows to prevent concurrent executions of the task
+		// (true or the name of a lock shared by many tasks).
+		Lock orbitLock `yaml:"lock,omitempty"`
+
+		// Output is the way the output of the commands is displayed:
+		// interleaved (default), prefixed, grouped or file.
+		Output string `yaml:"output,omitempty"`
+
+		// Sources array contains the patterns of the files read by the commands.
+		// Their content is part of the cache key of the task.
+		Sources []string `yaml:"sources,omitempty"`
+
+		// Generates array contains the patterns of the files generated by the commands.
+		Generates []string `yaml:"generates,omitempty"`
+
+		// Cache allows to restore the generated files from the cache
+		// instead of running the commands. Requires sources and generated files.
+		Cache bool `yaml:"cache,omitempty"`
+
+		// Env array contains the names of the environment variables
+		// whose values are part of the cache key of the task.
+		Env []string `yaml:"env,omitempty"`
+
+		// TTY allows to run the commands inside a pseudo-terminal
+		// when the Stdout of Orbit is a terminal.
+		TTY bool `yaml:"tty,omitempty"`
+
 		// Run is the stack of commands to execute.
 		Run []string `yaml:"run"`
+
+		// rendered is true once the attributes of the task have been rendered.
+		rendered bool
+
+		// inputsRendered is true once the prompt and the inputs of the task have been rendered.
+		inputsRendered bool
+
+		// answers map contains the values of the inputs, once given by the user.
+		answers map[string]interface{}
 	}
 
 	// OrbitRunner helps executing tasks.
@@ -63,32 +140,185 @@ type (
 
 		// context is an instance of OrbitContext.
 		context *context.OrbitContext
+
+		// generator renders the tasks once they are needed.
+		// It is nil if the configuration file has already been rendered.
+		generator *generator.OrbitGenerator
+
+		// report contains the results of the executed tasks.
+		report *orbitJUnitReport
+
+		// logDir is the optional directory of the log files.
+		logDir string
+
+		// logged contains the log files which have already been opened.
+		logged map[string]bool
+
+		// prompter asks the confirmations and the inputs of the tasks.
+		prompter *orbitPrompter
+
+		// lockTimeout is how long a task waits for a lock held by another process.
+		lockTimeout time.Duration
+
+		// locks contains the lock files held by the runner.
+		locks map[string]bool
+
+		// cache stores the files generated by the tasks, nil if disabled.
+		cache *OrbitCache
+
+		// remote shares the files generated by the tasks over HTTP, nil if disabled.
+		remote *orbitRemoteCache
+
+		// depth is the number of nested calls of Run function.
+		depth int
+
+		// stdin is the reader of the commands' Stdin.
+		stdin io.Reader
+
+		// stdout is the writer of the commands' Stdout.
+		stdout io.Writer
+
+		// stderr is the writer of the commands' Stderr.
+		stderr io.Writer
+
+		// terminal is true if the writer of the commands' Stdout is a terminal.
+		terminal bool
+
+		// env contains additional environment variables given to the commands.
+		env []string
+
+		// dir is the optional working directory of the commands.
+		dir string
 	}
 )
 
 // NewOrbitRunner creates an instance of OrbitRunner.
-func NewOrbitRunner(context *context.OrbitContext) (*OrbitRunner, error) {
-	// first retrieves the data from the configuration file...
+func NewOrbitRunner(ctx gocontext.Context, context *context.OrbitContext) (*OrbitRunner, error) {
+	// the configuration file may have been read from Stdin.
+	data := context.TemplateContent
+	if data == nil {
+		var err error
+		if data, err = ioutil.ReadFile(context.TemplateFilePath); err != nil {
+			return nil, OrbitError.NewOrbitErrorf("unable to read the configuration file %s. Details:\n%s", context.TemplateFilePath, err)
+		}
+	}
+
+	// if the configuration file is a valid YAML file, each task is rendered with its own variables...
+	var config = &orbitRunnerConfig{}
+	if err := yaml.Unmarshal(data, &config); err == nil {
+		r := newOrbitRunner(context, config)
+		if err := r.render(ctx); err != nil {
+			return nil, err
+		}
+
+		if err := r.configureRemoteCache(); err != nil {
+			return nil, err
+		}
+
+		return r, nil
+	}
+
+	logger.Debugf("configuration file %s is not a valid YAML file before being rendered, rendering it as a whole", context.TemplateFilePath)
+
+	// ...otherwise retrieves the data from the whole configuration file...
 	g := generator.NewOrbitGenerator(context)
-	data, err := g.Execute()
+	rendered, err := g.Execute(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// then populates the orbitRunnerConfig.
+	r, err := NewOrbitRunnerFromData(context, rendered.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// the variables would be silently ignored, as the whole configuration file has been rendered without them.
+	if r.hasVars() {
+		return nil, OrbitError.NewOrbitErrorf("configuration file %s defines variables but is not a valid YAML file before being rendered: quote the values starting with {{ or remove the variables", context.TemplateFilePath)
+	}
+
+	return r, nil
+}
+
+// NewOrbitRunnerFromData creates an instance of OrbitRunner from an already rendered configuration.
+func NewOrbitRunnerFromData(context *context.OrbitContext, data []byte) (*OrbitRunner, error) {
 	var config = &orbitRunnerConfig{}
-	if err := yaml.Unmarshal(data.Bytes(), &config); err != nil {
+	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, OrbitError.NewOrbitErrorf("configuration file %s is not a valid YAML file. Details:\n%s", context.TemplateFilePath, err)
 	}
 
+	r := newOrbitRunner(context, config)
+	if err := r.configureRemoteCache(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// hasVars returns true if the configuration defines global or task variables.
+func (r *OrbitRunner) hasVars() bool {
+	if len(r.config.Vars) > 0 {
+		return true
+	}
+
+	for _, task := range r.config.Tasks {
+		if len(task.Vars) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newOrbitRunner creates an instance of OrbitRunner from a configuration.
+func newOrbitRunner(context *context.OrbitContext, config *orbitRunnerConfig) *OrbitRunner {
 	r := &OrbitRunner{
-		config:  config,
-		context: context,
+		config:   config,
+		context:  context,
+		report:   &orbitJUnitReport{},
+		logged:   make(map[string]bool),
+		prompter: newPrompter(false),
+		locks:    make(map[string]bool),
+		cache:    NewOrbitCache(DefaultCacheDir()),
+		stdin:    os.Stdin,
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+		terminal: terminal.IsTerminal(int(os.Stdout.Fd())),
 	}
 
 	logger.Debugf("runner has been instantiated with config %v and context %s", r.config, r.context)
 
-	return r, nil
+	return r
+}
+
+// SetStdio sets the Stdin, Stdout and Stderr of the commands and of the prompts.
+func (r *OrbitRunner) SetStdio(stdin io.Reader, stdout io.Writer, stderr io.Writer) {
+	r.stdin = stdin
+	r.stdout = stdout
+	r.stderr = stderr
+
+	// the prompts are only interactive if they read from the terminal.
+	r.prompter.interactive = r.prompter.interactive && stdin == os.Stdin
+	r.prompter.src = stdin
+	r.prompter.in = bufio.NewReader(stdin)
+	r.prompter.out = stderr
+
+	if f, ok := stdout.(*os.File); ok {
+		r.terminal = terminal.IsTerminal(int(f.Fd()))
+	} else {
+		r.terminal = false
+	}
+}
+
+// SetEnv sets additional environment variables given to the commands.
+func (r *OrbitRunner) SetEnv(env []string) {
+	r.env = env
+}
+
+// SetDir sets the working directory of the commands.
+func (r *OrbitRunner) SetDir(dir string) {
+	r.dir = dir
 }
 
 // Print prints the available tasks from the configuration file
@@ -101,9 +331,18 @@ func (r *OrbitRunner) Print() {
 	fmt.Fprint(w, "\nAvailable tasks:")
 
 	for _, task := range r.config.Tasks {
-		if !task.Private {
-			fmt.Fprintf(w, "\n  %s\t%s", task.Use, task.Short)
+		if task.Private {
+			continue
+		}
+
+		// the short description is displayed as is if the task cannot be rendered yet.
+		short, err := r.renderText(gocontext.Background(), task, task.Short)
+		if err != nil {
+			logger.Debugf("unable to render the short description of task %s. Details:\n%s", task.Use, err)
+			short = task.Short
 		}
+
+		fmt.Fprintf(w, "\n  %s\t%s", task.Use, short)
 	}
 
 	// clears the writer as it may contain some weird characters.
@@ -112,8 +351,13 @@ func (r *OrbitRunner) Print() {
 	w.Flush()
 }
 
-// Run runs the given tasks.
-func (r *OrbitRunner) Run(names ...string) error {
+/*
+Run runs the given tasks.
+
+Once the given context is done, the running command and its child processes
+are killed and the remaining commands are not executed.
+*/
+func (r *OrbitRunner) Run(ctx gocontext.Context, names ...string) error {
 	// populates an array of instances of orbitTask.
 	// if a given name doest not match with any tasks defined in the configuration file, throws an error.
 	tasks := make([]*orbitTask, len(names))
@@ -122,11 +366,28 @@ func (r *OrbitRunner) Run(names ...string) error {
 		if tasks[index] == nil {
 			return OrbitError.NewOrbitErrorf("task %s does not exist in configuration file %s", name, r.context.TemplateFilePath)
 		}
+
+		// the tasks are rendered before running anything.
+		if err := r.renderTask(ctx, tasks[index]); err != nil {
+			return err
+		}
+	}
+
+	// the before_all and after_all hooks are not executed for nested tasks.
+	if r.depth > 0 {
+		return r.runAll(ctx, tasks)
 	}
 
-	// alright, let's run each task.
+	return r.runAllWithHooks(ctx, tasks)
+}
+
+// runAll runs each given task.
+func (r *OrbitRunner) runAll(ctx gocontext.Context, tasks []*orbitTask) error {
+	r.depth++
+	defer func() { r.depth-- }()
+
 	for _, task := range tasks {
-		if err := r.run(task); err != nil {
+		if err := r.runWithHooks(ctx, task); err != nil {
 			return err
 		}
 	}
@@ -146,34 +407,125 @@ func (r *OrbitRunner) getTask(name string) *orbitTask {
 }
 
 // run executes the stack of commands from the given task.
-func (r *OrbitRunner) run(task *orbitTask) error {
+func (r *OrbitRunner) run(ctx gocontext.Context, task *orbitTask) (err error) {
 	if task.Short == "" {
 		logger.Infof("running task %s", task.Use)
 	} else {
 		logger.Infof("running task %s: %s", task.Use, task.Short)
 	}
 
-	for _, cmd := range task.Run {
-		// check if the current command is calling others tasks.
-		tasks := r.interpret(cmd)
-		if tasks != nil {
-			if err := r.Run(tasks...); err != nil {
-				return err
-			}
-		} else {
-			e := r.buildCommand(cmd, task)
-			e.Stdout = os.Stdout
-			e.Stderr = os.Stderr
-			e.Stdin = os.Stdin
+	if err := checkCache(task); err != nil {
+		return err
+	}
+
+	// the inputs are given to the commands as environment variables.
+	env, err := r.prompter.prompt(task)
+	if err != nil {
+		return err
+	}
+
+	if err := r.renderTask(ctx, task); err != nil {
+		return err
+	}
+
+	if task.Lock.enabled {
+		lock, err := r.acquireLock(ctx, task)
+		if err != nil {
+			return err
+		}
 
-			logger.Infof("executing command %s from task %s", e.Args, task.Use)
+		if lock != nil {
+			defer lock.release()
+		}
+	}
 
-			if err := e.Run(); err != nil {
-				return err
+	output, err := r.newTaskOutput(task)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if flushErr := output.flush(); flushErr != nil && err == nil {
+			err = OrbitError.NewOrbitErrorf("unable to flush the output of task %s. Details:\n%s", task.Use, flushErr)
+		}
+	}()
+
+	start := time.Now()
+	suite := r.report.newJUnitTestSuite(task.Use, start)
+	defer func() { suite.done(time.Since(start)) }()
+
+	// the generated files may be restored from the cache instead of running the commands.
+	key, restored, ok := r.restoreFromCache(ctx, task, env)
+	if ok {
+		reason := "the generated files have been restored from the cache"
+		if restored == 0 {
+			reason = "the generated files are up to date"
+		}
+
+		for _, cmd := range task.Run {
+			suite.skipped(cmd, reason)
+		}
+
+		return nil
+	}
+
+	for index, cmd := range task.Run {
+		if err := r.runCommand(ctx, cmd, task, env, output, suite); err != nil {
+			// the remaining commands will not be executed.
+			for _, remaining := range task.Run[index+1:] {
+				suite.skipped(remaining, "a previous command has failed")
 			}
+
+			return err
 		}
 	}
 
+	if key != "" {
+		r.storeInCache(ctx, task, key)
+	}
+
+	return nil
+}
+
+// runCommand executes a command from the given task and records its result.
+func (r *OrbitRunner) runCommand(ctx gocontext.Context, cmd string, task *orbitTask, env []string, output *orbitTaskOutput, suite *orbitJUnitTestSuite) error {
+	start := time.Now()
+
+	// check if the current command is calling others tasks.
+	tasks := r.interpret(cmd)
+	if tasks != nil {
+		if err := r.Run(ctx, tasks...); err != nil {
+			suite.failed(cmd, time.Since(start), err, "")
+			return err
+		}
+
+		suite.passed(cmd, time.Since(start))
+		return nil
+	}
+
+	// the secrets are masked before truncating Stderr, so that a secret is never cut.
+	stderr := newTailBuffer(maxJUnitStderr)
+	masked := newMaskWriter(stderr)
+	e := r.prepareCommand(ctx, cmd, task, env)
+	e.Stdout = output.stdout
+	e.Stderr = io.MultiWriter(output.stderr, masked)
+
+	logger.Infof("executing command %s from task %s", e.Args, task.Use)
+
+	var err error
+	if task.TTY && r.terminal {
+		err = r.executeInTerminal(ctx, e)
+	} else {
+		err = r.execute(ctx, e, task.foreground())
+	}
+
+	masked.flush()
+	if err != nil {
+		suite.failed(cmd, time.Since(start), err, stderr.String())
+		return err
+	}
+
+	suite.passed(cmd, time.Since(start))
 	return nil
 }
 
@@ -195,21 +547,90 @@ func (r *OrbitRunner) interpret(cmd string) []string {
 	return strings.Split(match[1], ",")
 }
 
+// prepareCommand returns an exec.Cmd instance with the Stdin, the environment variables
+// and the working directory of the runner.
+func (r *OrbitRunner) prepareCommand(ctx gocontext.Context, cmd string, task *orbitTask, env []string) *exec.Cmd {
+	e := r.buildCommand(ctx, cmd, task)
+	e.Stdin = r.stdin
+	e.Dir = r.dir
+	if len(r.env) > 0 || len(env) > 0 {
+		e.Env = append(append(os.Environ(), r.env...), env...)
+	}
+
+	return e
+}
+
+/*
+execute runs the given command until it exits or the given context is done.
+
+In the latter case, the command and its child processes are killed. The command
+runs in its own process group, so that Orbit stays in the foreground and receives
+the interrupts of the terminal: a command reading from the terminal would then be
+stopped, unless it interacts with the user (see foreground). If so and if Stdin is
+a terminal, its process group becomes the foreground one until it exits.
+*/
+func (r *OrbitRunner) execute(ctx gocontext.Context, e *exec.Cmd, foreground bool) error {
+	if e.SysProcAttr == nil {
+		tty := int(os.Stdin.Fd())
+		if foreground && r.prompter.interactive && setForegroundProcessGroup(e, tty) {
+			defer func() {
+				if err := restoreForegroundProcessGroup(tty); err != nil {
+					logger.Debugf("unable to restore the foreground process group. Details:\n%s", err)
+				}
+			}()
+		} else {
+			setProcessGroup(e)
+		}
+	}
+
+	if err := e.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := killProcessTree(e.Process); err != nil {
+				logger.Debugf("unable to kill the process %d. Details:\n%s", e.Process.Pid, err)
+			}
+		case <-done:
+		}
+	}()
+
+	err := e.Wait()
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+/*
+foreground returns true if the commands of the task interact with the user, i.e. if the task
+runs inside a pseudo-terminal or asks for inputs: they may then read from the terminal.
+*/
+func (task *orbitTask) foreground() bool {
+	return task.TTY || len(task.Inputs) > 0
+}
+
 // buildCommand returns an exec.Cmd instance.
-func (r *OrbitRunner) buildCommand(cmd string, task *orbitTask) *exec.Cmd {
+func (r *OrbitRunner) buildCommand(ctx gocontext.Context, cmd string, task *orbitTask) *exec.Cmd {
 	if task.Shell != "" {
 		// the user has specified a custom binary to use.
 		shellAndParams := strings.Fields(task.Shell)
 		shell := shellAndParams[0]
 		parameters := append(shellAndParams[1:], cmd)
 
-		return exec.Command(shell, parameters...)
+		return exec.CommandContext(ctx, shell, parameters...)
 	}
 
 	// if no custom binary specified, detects the current shell of the user.
 	if runtime.GOOS == "windows" {
-		return exec.Command(os.Getenv(defaultWindowsShellEnvVariable), "/c", cmd)
+		return exec.CommandContext(ctx, os.Getenv(defaultWindowsShellEnvVariable), "/c", cmd)
 	}
 
-	return exec.Command(os.Getenv(defaultPosixShellEnvVariable), "-c", cmd)
+	return exec.CommandContext(ctx, os.Getenv(defaultPosixShellEnvVariable), "-c", cmd)
 }
@@ -1,10 +1,19 @@
 package runner
 
 import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gulien/orbit/app/context"
+	"github.com/gulien/orbit/app/logger"
 )
 
 // Tests if initializing an OrbitRunner throws an error
@@ -13,22 +22,22 @@ import (
 func TestNewOrbitRunner(t *testing.T) {
 	// case 1: uses a wrong configuration file.
 	wrongTemplateFilePath, _ := filepath.Abs("../../_tests/.env")
-	ctx, _ := context.NewOrbitContext(wrongTemplateFilePath, "", "", nil)
-	if _, err := NewOrbitRunner(ctx); err == nil {
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), wrongTemplateFilePath, "", "", nil)
+	if _, err := NewOrbitRunner(gocontext.Background(), ctx); err == nil {
 		t.Error("OrbitRunner should not have been instantiated!")
 	}
 
 	// case 2: uses a broken configuration file.
 	brokenTemplateFilePath, _ := filepath.Abs("../../_tests/broken-template.yml")
-	ctx, _ = context.NewOrbitContext(brokenTemplateFilePath, "", "", nil)
-	if _, err := NewOrbitRunner(ctx); err == nil {
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), brokenTemplateFilePath, "", "", nil)
+	if _, err := NewOrbitRunner(gocontext.Background(), ctx); err == nil {
 		t.Error("OrbitRunner should not have been instantiated!")
 	}
 
 	// case 3 uses a correct configuration file.
 	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
-	ctx, _ = context.NewOrbitContext(templateFilePath, "", "", nil)
-	if _, err := NewOrbitRunner(ctx); err != nil {
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	if _, err := NewOrbitRunner(gocontext.Background(), ctx); err != nil {
 		t.Error("OrbitRunner should have been instantiated!")
 	}
 }
@@ -36,8 +45,8 @@ func TestNewOrbitRunner(t *testing.T) {
 // A dumb test to improve code coverage.
 func TestPrint(t *testing.T) {
 	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "", "", nil)
-	r, _ := NewOrbitRunner(ctx)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
 
 	r.Print()
 }
@@ -45,51 +54,651 @@ func TestPrint(t *testing.T) {
 // Tests Run function by running different kind of tasks.
 func TestRun(t *testing.T) {
 	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
-	ctx, _ := context.NewOrbitContext(templateFilePath, "", "", nil)
-	r, _ := NewOrbitRunner(ctx)
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
 
 	// case 1: uses a non existing task.
-	if err := r.Run("discovery"); err == nil {
+	if err := r.Run(gocontext.Background(), "discovery"); err == nil {
 		t.Error("Task should not exist!")
 	}
 
 	// case 2: uses a task which has a non existing command.
-	if err := r.Run("challenger"); err == nil {
+	if err := r.Run(gocontext.Background(), "challenger"); err == nil {
 		t.Error("Task should have failed!")
 	}
 
 	// case 3: uses a correct task.
-	if err := r.Run("explorer"); err != nil {
+	if err := r.Run(gocontext.Background(), "explorer"); err != nil {
 		t.Error("Task should have been run!")
 	}
 
 	// case 4: uses nested tasks.
-	if err := r.Run("explorer", "sputnik"); err != nil {
+	if err := r.Run(gocontext.Background(), "explorer", "sputnik"); err != nil {
 		t.Error("Nested tasks should have been run!")
 	}
 
 	// case 5: uses custom shell with non-existent shell.
-	if err := r.Run("zuma"); err == nil {
+	if err := r.Run(gocontext.Background(), "zuma"); err == nil {
 		t.Error("Custom shell task should have failed!")
 	}
 
 	// case 6: uses custom shell with existent shell.
-	if err := r.Run("falcon"); err != nil {
+	if err := r.Run(gocontext.Background(), "falcon"); err != nil {
 		t.Error("Custom shell task should have been run!")
 	}
 
 	// case 7: uses custom shell without parameter.
-	if err := r.Run("ariane"); err != nil {
+	if err := r.Run(gocontext.Background(), "ariane"); err != nil {
 		t.Error("Custom shell task should have been run!")
 	}
 
 	// case 8: uses a task which calls others tasks
-	if err := r.Run("new shepard"); err != nil {
+	if err := r.Run(gocontext.Background(), "new shepard"); err != nil {
 		t.Error("Task calling others tasks should have been run!")
 	}
 
 	// case 9: uses a task which calls a non existing task.
-	if err := r.Run("new glenn"); err == nil {
+	if err := r.Run(gocontext.Background(), "new glenn"); err == nil {
 		t.Error("Task calling another task should not have been run!")
 	}
 }
+
+// Tests if the JUnit report contains the executed tasks.
+func TestWriteJUnitReport(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	r.Run(gocontext.Background(), "new shepard")
+	r.Run(gocontext.Background(), "challenger")
+
+	// case 1: uses a broken output path.
+	if err := r.WriteJUnitReport("/.../..."); err == nil {
+		t.Error("OrbitRunner should not have been able to write the JUnit report /...!")
+	}
+
+	// case 2: uses a correct output path.
+	if err := r.WriteJUnitReport("report.xml"); err != nil {
+		t.Error("OrbitRunner should have been able to write the JUnit report report.xml!")
+	}
+
+	data, _ := ioutil.ReadFile("report.xml")
+	os.Remove("report.xml")
+
+	var report orbitJUnitReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Error("report.xml should be a valid XML file!")
+	}
+
+	// "new shepard" calls "explorer" and "sputnik".
+	if len(report.Suites) != 4 {
+		t.Errorf("JUnit report should contain 4 test suites, got %d!", len(report.Suites))
+	}
+
+	if report.Suites[3].Name != "challenger" || report.Suites[3].Failures != 1 {
+		t.Error("JUnit report should contain the failure of task challenger!")
+	}
+
+	if c := report.Suites[3].Cases[0]; c.Failure == nil || c.Failure.Message != "exit status 127" || !strings.Contains(c.SystemErr, "failecho") {
+		t.Error("JUnit report should contain the exit status and the Stderr of the failed command of task challenger!")
+	}
+
+	if _, err := time.Parse(time.RFC3339, report.Suites[0].Timestamp); err != nil {
+		t.Errorf("JUnit report should contain RFC 3339 timestamps, got %s!", report.Suites[0].Timestamp)
+	}
+}
+
+// Tests if the tail buffer only keeps the last bytes written into it.
+func TestTailBuffer(t *testing.T) {
+	// case 1: writes less bytes than the maximum size.
+	b := newTailBuffer(8)
+	b.Write([]byte("abc"))
+	b.Write([]byte("def"))
+	if b.String() != "abcdef" {
+		t.Errorf("Tail buffer should have kept every byte, got %q!", b.String())
+	}
+
+	// case 2: writes more bytes than the maximum size.
+	b.Write([]byte("ghijk"))
+	if b.String() != "[...]\ndefghijk" {
+		t.Errorf("Tail buffer should have kept the last bytes, got %q!", b.String())
+	}
+
+	// case 3: writes a single chunk larger than the maximum size.
+	b = newTailBuffer(4)
+	b.Write([]byte("abcdefgh"))
+	if b.String() != "[...]\nefgh" {
+		t.Errorf("Tail buffer should have kept the last bytes, got %q!", b.String())
+	}
+
+	// case 4: writes a secret through a mask writer, which masks it before it is truncated.
+	logger.AddSecrets("t41l-s3cr3t")
+	b = newTailBuffer(8)
+	w := newMaskWriter(b)
+	w.Write([]byte("abc t41l-s3cr3t\n"))
+	w.flush()
+	if b.String() != "abc ***\n" {
+		t.Errorf("Tail buffer should have kept the masked bytes, got %q!", b.String())
+	}
+}
+
+// Tests if the output of the tasks is written according to their output mode.
+func TestOutput(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	logDir, _ := ioutil.TempDir("", "orbit")
+	defer os.RemoveAll(logDir)
+	r.SetLogDir(logDir)
+
+	// case 1: uses a task with an unknown output.
+	if err := r.Run(gocontext.Background(), "apollo"); err == nil {
+		t.Error("Task with an unknown output should not have been run!")
+	}
+
+	// case 2: uses tasks with the available outputs.
+	if err := r.Run(gocontext.Background(), "explorer", "soyuz", "vostok", "gemini"); err != nil {
+		t.Error("Tasks should have been run!")
+	}
+
+	for _, name := range []string{"explorer", "soyuz", "vostok", "gemini"} {
+		data, err := ioutil.ReadFile(filepath.Join(logDir, name+".log"))
+		if err != nil || string(data) != "I am "+name+" task\n" {
+			t.Errorf("Log file of task %s should contain the output of its command!", name)
+		}
+	}
+
+	// case 3: uses the grouped output with both streams.
+	var stdout, stderr bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &stdout, &stderr)
+	if err := r.Run(gocontext.Background(), "voskhod"); err != nil {
+		t.Error("Task should have been run!")
+	}
+
+	if stdout.String() != "I am voskhod task\n" || stderr.String() != "I am on Stderr\n" {
+		t.Errorf("Grouped output should have kept the streams separate, got %q and %q!", stdout.String(), stderr.String())
+	}
+
+	// case 4: uses a log directory relative to the working directory of the runner.
+	r.SetDir(logDir)
+	r.SetLogDir("logs")
+	if err := r.Run(gocontext.Background(), "explorer"); err != nil {
+		t.Error("Task should have been run!")
+	}
+
+	if _, err := os.Stat(filepath.Join(logDir, "logs", "explorer.log")); err != nil {
+		t.Error("Log file should have been written into the log directory relative to the working directory!")
+	}
+}
+
+// Tests if the prefix writer prefixes each line.
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newPrefixWriter(&out, "[explorer] ")
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\nthird line"))
+	w.flush()
+
+	if out.String() != "[explorer] first line\n[explorer] second line\n[explorer] third line\n" {
+		t.Errorf("Prefix writer should have prefixed each line, got %q!", out.String())
+	}
+}
+
+// Tests if the mask writer masks the secrets of each line.
+func TestMaskWriter(t *testing.T) {
+	logger.AddSecrets("s3cr3t")
+
+	var out bytes.Buffer
+	w := newMaskWriter(&out)
+
+	w.Write([]byte("token: s3c"))
+	w.Write([]byte("r3t\nno line break: s3cr3t"))
+	w.flush()
+
+	if out.String() != "token: ***\nno line break: ***" {
+		t.Errorf("Mask writer should have masked the secrets, got %q!", out.String())
+	}
+
+	// case 2: writes an incomplete line, such as a prompt.
+	out.Reset()
+	w.Write([]byte("Password: "))
+	if out.String() != "Password: " {
+		t.Errorf("Mask writer should not have held back the incomplete line, got %q!", out.String())
+	}
+
+	// case 3: writes an incomplete line ending with the beginning of a secret.
+	out.Reset()
+	w.Write([]byte("token: s3cr"))
+	if out.String() != "token: " {
+		t.Errorf("Mask writer should only have held back the beginning of the secret, got %q!", out.String())
+	}
+
+	w.Write([]byte("3t and s3cr3t!"))
+	if out.String() != "token: *** and ***!" {
+		t.Errorf("Mask writer should have masked the secrets, got %q!", out.String())
+	}
+
+	// case 4: writes a multi-line secret.
+	out.Reset()
+	logger.AddSecrets("-----BEGIN KEY-----\nm1s3cr3t\n-----END KEY-----\n")
+	w.Write([]byte("key:\n-----BEGIN KEY-----\nm1s3cr3t\n-----END KEY-----\n"))
+	if out.String() != "key:\n***\n***\n***\n" {
+		t.Errorf("Mask writer should have masked each line of the secret, got %q!", out.String())
+	}
+}
+
+// Tests if the prompts of the tasks are answered as expected.
+func TestPrompt(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	// case 1: uses a non-interactive Stdin.
+	r.prompter = &orbitPrompter{interactive: false, out: ioutil.Discard}
+	if err := r.Run(gocontext.Background(), "mercury"); err == nil {
+		t.Error("Task requiring a confirmation should not have been run!")
+	}
+
+	// case 2: uses the --yes flag.
+	r.SetAssumeYes(true)
+	if err := r.Run(gocontext.Background(), "mercury"); err != nil {
+		t.Error("Task requiring a confirmation should have been run!")
+	}
+
+	// case 3: uses the default value of an input which is not the expected one.
+	if err := r.Run(gocontext.Background(), "vega"); err == nil {
+		t.Error("Task should have failed with the default value of its input!")
+	}
+
+	// case 4: uses a default value which is not one of the choices of the input.
+	if err := r.Run(gocontext.Background(), "proton"); err == nil {
+		t.Error("Task should not have been run with an invalid default value!")
+	}
+
+	// case 5: refuses the confirmation.
+	r.prompter = &orbitPrompter{interactive: true, in: bufio.NewReader(strings.NewReader("n\n")), out: ioutil.Discard}
+	if err := r.Run(gocontext.Background(), "mercury"); err == nil {
+		t.Error("Task should have been aborted!")
+	}
+
+	// case 6: gives an invalid choice then a valid one.
+	r.prompter = &orbitPrompter{interactive: true, in: bufio.NewReader(strings.NewReader("Ariane\nVega C\n")), out: ioutil.Discard}
+	if err := r.Run(gocontext.Background(), "vega"); err != nil {
+		t.Error("Task should have been run with the given value of its input!")
+	}
+
+	// case 7: gives a hidden value through the reader of the runner.
+	var out bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &out, ioutil.Discard)
+	r.prompter = &orbitPrompter{interactive: true, src: strings.NewReader(""), in: bufio.NewReader(strings.NewReader("t1t4n-typed\n")), out: ioutil.Discard}
+	if err := r.Run(gocontext.Background(), "titan"); err != nil || out.String() != "password: ***\n" {
+		t.Errorf("Hidden value should have been read and masked, got %q!", out.String())
+	}
+
+	// case 8: gives a hidden value through an environment variable.
+	out.Reset()
+	os.Setenv("ORBIT_TITAN_PASSWORD", "t1t4n-env")
+	defer os.Unsetenv("ORBIT_TITAN_PASSWORD")
+	if err := r.Run(gocontext.Background(), "titan"); err != nil || out.String() != "password: ***\n" {
+		t.Errorf("Hidden value given by an environment variable should have been masked, got %q!", out.String())
+	}
+
+	// case 9: gives a value which is not one of the choices through an environment variable.
+	os.Setenv("ORBIT_LAUNCHER", "Ariane")
+	defer os.Unsetenv("ORBIT_LAUNCHER")
+	if err := r.Run(gocontext.Background(), "vega"); err == nil || !strings.Contains(err.Error(), "is not one of Vega, Vega C") {
+		t.Errorf("Task should not have been run with an invalid value, got %v!", err)
+	}
+}
+
+// Tests if a locked task is not run while another process holds its lock.
+func TestLock(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+	defer os.RemoveAll(".orbit")
+
+	// case 1: uses a lock which is not held.
+	if err := r.Run(gocontext.Background(), "gagarin"); err != nil {
+		t.Error("Locked task should have been run!")
+	}
+
+	// case 2: uses nested tasks sharing the same lock.
+	if err := r.Run(gocontext.Background(), "vostok 1"); err != nil {
+		t.Error("Nested tasks sharing the same lock should have been run!")
+	}
+
+	// case 3: uses a lock held by "another process".
+	file, _ := os.OpenFile(filepath.Join(defaultLockDir, "gagarin.lock"), os.O_RDWR, 0644)
+	defer file.Close()
+	if locked, err := tryLockFile(file); !locked || err != nil {
+		t.Fatal("Lock should have been acquired!")
+	}
+
+	r.SetLockTimeout(200 * time.Millisecond)
+	if err := r.Run(gocontext.Background(), "gagarin"); err == nil {
+		t.Error("Locked task should not have been run!")
+	}
+
+	// case 4: uses a lock which has been released.
+	unlockFile(file)
+	if err := r.Run(gocontext.Background(), "gagarin"); err != nil {
+		t.Error("Locked task should have been run once the lock has been released!")
+	}
+}
+
+// Tests if each task is rendered with its own variables.
+func TestVars(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit-vars.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "launcher,Ariane 5;site,Cape Canaveral", "", nil)
+	r, err := NewOrbitRunner(gocontext.Background(), ctx)
+	if err != nil {
+		t.Fatal("OrbitRunner should have been instantiated!")
+	}
+
+	var stdout bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &stdout, ioutil.Discard)
+
+	// case 1: uses the global variables over the payload.
+	if err := r.Run(gocontext.Background(), "falcon"); err != nil || stdout.String() != "Falcon 9 by SpaceX from Cape Canaveral\nFalcon 9 has landed\n" {
+		t.Errorf("Task should have been rendered with the global variables, got %q!", stdout.String())
+	}
+
+	// case 2: uses the variables of the task over the global variables.
+	stdout.Reset()
+	if err := r.Run(gocontext.Background(), "heavy"); err != nil || stdout.String() != "Falcon Heavy by SpaceX from Cape Canaveral\nFalcon 9 has landed\n" {
+		t.Errorf("Task should have been rendered with its variables, got %q!", stdout.String())
+	}
+
+	// case 3: uses a task calling a task with others variables.
+	stdout.Reset()
+	if err := r.Run(gocontext.Background(), "starship"); err != nil || stdout.String() != "Falcon 9 by SpaceX from Cape Canaveral\nStarship\nFalcon 9 has landed\n" {
+		t.Errorf("Each task should have been rendered with its own variables, got %q!", stdout.String())
+	}
+
+	// case 4: uses values given by the user over the variables.
+	ctx.Overrides, _ = context.ParseOverrides([]string{"launcher=New Glenn", "Values.agency=Blue Origin"})
+	r, _ = NewOrbitRunner(gocontext.Background(), ctx)
+	stdout.Reset()
+	r.SetStdio(strings.NewReader(""), &stdout, ioutil.Discard)
+	if err := r.Run(gocontext.Background(), "heavy"); err != nil || stdout.String() != "New Glenn by Blue Origin from Cape Canaveral\nNew Glenn has landed\n" {
+		t.Errorf("Task should have been rendered with the values given by the user, got %q!", stdout.String())
+	}
+
+	// case 5: uses the inputs of the task.
+	stdout.Reset()
+	r.SetAssumeYes(true)
+	if err := r.Run(gocontext.Background(), "soyuz"); err != nil || stdout.String() != "Gagarin on board\nNew Glenn has landed\n" {
+		t.Errorf("Task should have been rendered with its inputs, got %q!", stdout.String())
+	}
+
+	// case 6: uses variables in a configuration file which is not a valid YAML file before being rendered.
+	templateFilePath, _ = filepath.Abs("../../_tests/orbit-vars-not-yaml.yml")
+	ctx, _ = context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	if _, err := NewOrbitRunner(gocontext.Background(), ctx); err == nil || !strings.Contains(err.Error(), "defines variables") {
+		t.Errorf("OrbitRunner should not have been instantiated with ignored variables, got %v!", err)
+	}
+}
+
+// Tests if a task is only rendered once it is needed.
+func TestRenderTask(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit-vars.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "site,Cape Canaveral", "", nil)
+	r, err := NewOrbitRunner(gocontext.Background(), ctx)
+	if err != nil {
+		t.Fatal("OrbitRunner should have been instantiated even if a task uses a missing data!")
+	}
+
+	r.SetStdio(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+
+	// case 1: uses a task which does not use the missing data.
+	if err := r.Run(gocontext.Background(), "falcon"); err != nil {
+		t.Error("Task should have been run!")
+	}
+
+	// case 2: uses the task which uses the missing data.
+	err = r.Run(gocontext.Background(), "deploy")
+	if err == nil || !strings.Contains(err.Error(), "task deploy ("+templateFilePath+":22)") {
+		t.Errorf("Task should not have been rendered, got %v!", err)
+	}
+
+	if err := r.Explain(gocontext.Background(), ioutil.Discard, "deploy"); err == nil {
+		t.Error("Task should not have been explained!")
+	}
+
+	var graph bytes.Buffer
+	if err := r.Graph(gocontext.Background(), &graph, TreeGraphFormat); err != nil || !strings.Contains(graph.String(), "falcon\n") || strings.Contains(graph.String(), "deploy") {
+		t.Errorf("Graph of all the tasks should have been printed without the task deploy, got %q!", graph.String())
+	}
+
+	if err := r.Graph(gocontext.Background(), ioutil.Discard, TreeGraphFormat, "deploy"); err == nil {
+		t.Error("Graph of the task deploy should not have been printed!")
+	}
+
+	// case 3: uses the missing data given by the user.
+	ctx.Overrides = map[string]interface{}{"Secret": "s3cr3t"}
+	r, _ = NewOrbitRunner(gocontext.Background(), ctx)
+	r.SetStdio(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	if err := r.Run(gocontext.Background(), "deploy"); err != nil {
+		t.Error("Task should have been rendered and run!")
+	}
+}
+
+// Tests if a task is stopped once its context is done.
+func TestRunCancel(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	// case 1: uses a cancelled context.
+	cancelled, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if err := r.Run(cancelled, "explorer"); err == nil {
+		t.Error("Task should not have been run with a cancelled context!")
+	}
+
+	// case 2: uses a timeout shorter than the task, whose child processes hold its output.
+	var stdout bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &stdout, ioutil.Discard)
+	timeout, cancel := gocontext.WithTimeout(gocontext.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := r.Run(timeout, "luna"); err == nil {
+		t.Error("Task should have been stopped by the timeout!")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Error("Child processes of the task should have been killed!")
+	}
+
+	if strings.Contains(stdout.String(), "I should not have been run") {
+		t.Error("Remaining commands of the task should not have been run!")
+	}
+
+	// case 3: uses a timeout with a task whose command stops itself.
+	stdout.Reset()
+	timeout, cancel = gocontext.WithTimeout(gocontext.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	if err := r.Run(timeout, "mir"); err == nil {
+		t.Error("Stopped command should have been killed by the timeout!")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Error("Stopped command should not have blocked Orbit!")
+	}
+
+	if strings.Contains(stdout.String(), "I should not have been run") {
+		t.Error("Remaining commands of the task should not have been run!")
+	}
+}
+
+// Tests if a task runs inside a pseudo-terminal when the Stdout of Orbit is a terminal.
+func TestTTY(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	var stdout bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &stdout, ioutil.Discard)
+
+	// case 1: uses a Stdout which is not a terminal.
+	if err := r.Run(gocontext.Background(), "salyut"); err == nil {
+		t.Error("Task should not have been run inside a pseudo-terminal!")
+	}
+
+	// case 2: uses a Stdout which is a terminal.
+	stdout.Reset()
+	r.terminal = true
+	if err := r.Run(gocontext.Background(), "salyut"); err != nil {
+		t.Error("Task should have been run inside a pseudo-terminal!")
+	}
+
+	if stdout.String() != "[salyut] I am salyut task\n[salyut] I am in a terminal\n" {
+		t.Errorf("Output of the pseudo-terminal should have been prefixed, got %q!", stdout.String())
+	}
+}
+
+// Tests if the graph of the tasks is printed in the available formats.
+func TestGraph(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	// case 1: uses a non existing task.
+	if err := r.Graph(gocontext.Background(), ioutil.Discard, TreeGraphFormat, "discovery"); err == nil {
+		t.Error("Graph of a non existing task should not have been printed!")
+	}
+
+	// case 2: uses an unknown format.
+	if err := r.Graph(gocontext.Background(), ioutil.Discard, "svg"); err == nil {
+		t.Error("Graph should not have been printed with an unknown format!")
+	}
+
+	// case 3: uses the available formats.
+	expected := map[string]string{
+		TreeGraphFormat:    "new glenn\n└── vulcan (missing)\n",
+		DOTGraphFormat:     "digraph orbit {\n  \"new glenn\";\n  \"vulcan\" [style=dashed];\n  \"new glenn\" -> \"vulcan\";\n}\n",
+		MermaidGraphFormat: "graph TD\n  task0[\"new glenn\"]\n  task1[\"vulcan\"]\n  task0 --> task1\n",
+	}
+
+	for format, graph := range expected {
+		var out bytes.Buffer
+		if err := r.Graph(gocontext.Background(), &out, format, "new glenn"); err != nil || out.String() != graph {
+			t.Errorf("Graph should have been printed in format %s, got %q!", format, out.String())
+		}
+	}
+}
+
+// Tests if the definition of a task is printed.
+func TestExplain(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	// case 1: uses a non existing task.
+	if err := r.Explain(gocontext.Background(), ioutil.Discard, "discovery"); err == nil {
+		t.Error("Definition of a non existing task should not have been printed!")
+	}
+
+	// case 2: uses a task which calls others tasks.
+	var out bytes.Buffer
+	if err := r.Explain(gocontext.Background(), &out, "new shepard"); err != nil {
+		t.Error("Definition of the task should have been printed!")
+	}
+
+	if !strings.Contains(out.String(), templateFilePath+":25") || !strings.Contains(out.String(), "runs task(s) explorer, sputnik") {
+		t.Errorf("Definition of the task is not the expected one, got %q!", out.String())
+	}
+
+	// case 3: uses a task whose commands contain a secret.
+	logger.AddSecrets("new glenn task")
+	out.Reset()
+	if err := r.Explain(gocontext.Background(), &out, "new glenn"); err != nil {
+		t.Error("Definition of the task should have been printed!")
+	}
+
+	if strings.Contains(out.String(), "new glenn task") || !strings.Contains(out.String(), `echo "I am ***"`) {
+		t.Errorf("Secrets of the task should have been masked, got %q!", out.String())
+	}
+}
+
+// Tests if the hooks are executed around the tasks.
+func TestHooks(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../../_tests/orbit-hooks.yml")
+	ctx, _ := context.NewOrbitContext(gocontext.Background(), templateFilePath, "", "", nil)
+	r, _ := NewOrbitRunner(gocontext.Background(), ctx)
+
+	hooksLog, _ := ioutil.TempFile("", "orbit")
+	hooksLog.Close()
+	defer os.Remove(hooksLog.Name())
+	os.Setenv("ORBIT_HOOKS_LOG", hooksLog.Name())
+	defer os.Unsetenv("ORBIT_HOOKS_LOG")
+
+	// case 1: uses a task which calls another task.
+	if err := r.Run(gocontext.Background(), "new shepard"); err != nil {
+		t.Error("Task should have been run!")
+	}
+
+	// case 2: uses a failing task.
+	if err := r.Run(gocontext.Background(), "challenger"); err == nil {
+		t.Error("Task should have failed!")
+	}
+
+	// case 3: uses a task with a failing before_each hook.
+	if err := r.Run(gocontext.Background(), "zuma"); err == nil {
+		t.Error("Task should not have been run!")
+	}
+
+	data, _ := ioutil.ReadFile(hooksLog.Name())
+	expected := "before_all:new shepard\n" +
+		"after_each:explorer:success\n" +
+		"after_each:new shepard:success\n" +
+		"after_all:new shepard:success\n" +
+		"before_all:challenger\n" +
+		"on_failure:challenger\n" +
+		"after_each:challenger:failure\n" +
+		"after_all:challenger:failure\n" +
+		"before_all:zuma\n" +
+		"on_failure:zuma\n" +
+		"after_all:zuma:failure\n"
+
+	if string(data) != expected {
+		t.Errorf("Hooks have not been executed as expected, got %q!", string(data))
+	}
+
+	// case 4: uses a hook which prints a secret.
+	var out bytes.Buffer
+	r.SetStdio(strings.NewReader(""), &out, ioutil.Discard)
+	r.SetEnv([]string{"ORBIT_HOOKS_TOKEN=h00k-t0k3n"})
+	logger.AddSecrets("h00k-t0k3n")
+	if err := r.Run(gocontext.Background(), "explorer"); err != nil {
+		t.Error("Task should have been run!")
+	}
+
+	if strings.Contains(out.String(), "h00k-t0k3n") || !strings.Contains(out.String(), "token: ***\n") {
+		t.Errorf("Secrets of the output of the hooks should have been masked, got %q!", out.String())
+	}
+
+	// case 5: uses a context cancelled while the task runs.
+	ioutil.WriteFile(hooksLog.Name(), nil, 0644)
+	timeout, cancel := gocontext.WithTimeout(gocontext.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := r.Run(timeout, "luna"); err == nil {
+		t.Error("Task should have been stopped by the timeout!")
+	}
+
+	data, _ = ioutil.ReadFile(hooksLog.Name())
+	expected = "before_all:luna\n" +
+		"on_failure:luna\n" +
+		"after_each:luna:failure\n" +
+		"after_all:luna:failure\n"
+
+	if string(data) != expected {
+		t.Errorf("Final hooks should have been executed despite the cancelled context, got %q!", string(data))
+	}
+}
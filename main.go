@@ -32,8 +32,14 @@ var version = "master"
 func main() {
 	OrbitVersion.Current = version
 
-	if err := app.RootCmd.Execute(); err != nil {
+	if err := app.Execute(); err != nil {
 		logger.Error(err)
+
+		// like git, Orbit exits with the exit code of a plugin.
+		if exitErr, ok := err.(*app.PluginExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+
 		os.Exit(1)
 	}
 }
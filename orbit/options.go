@@ -0,0 +1,147 @@
+package orbit
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	OrbitError "github.com/gulien/orbit/app/error"
+)
+
+type (
+	// Option configures the rendering of a template or the execution of tasks.
+	Option func(*options)
+
+	// options contains the configuration given by the options.
+	options struct {
+		// ctx allows to cancel the rendering of the templates and the execution of the tasks.
+		ctx context.Context
+
+		// payload contains the data applied to the templates.
+		payload map[string]interface{}
+
+		// templates array contains the paths of additional templates.
+		templates []string
+
+		// delimiters is the pair of template delimiters.
+		delimiters []string
+
+		// stdin is the reader of the commands' Stdin.
+		stdin io.Reader
+
+		// stdout is the writer of the commands' Stdout.
+		stdout io.Writer
+
+		// stderr is the writer of the commands' Stderr.
+		stderr io.Writer
+
+		// env contains additional environment variables given to the commands.
+		env []string
+
+		// dir is the working directory of the commands.
+		dir string
+
+		// runnerOptions contains the names of the given options which only apply to the execution of the tasks.
+		runnerOptions []string
+	}
+)
+
+// newOptions creates an instance of options with the defaults values, then applies the given options.
+func newOptions(opts []Option) *options {
+	o := &options{
+		ctx:        context.Background(),
+		payload:    make(map[string]interface{}),
+		delimiters: make([]string, 2),
+		stdin:      os.Stdin,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithContext sets the context which allows to cancel the rendering of the templates
+// and the execution of the tasks, killing the running commands.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithPayload sets the data available in the templates through {{ .Orbit }}.
+func WithPayload(payload map[string]interface{}) Option {
+	return func(o *options) {
+		o.payload = payload
+	}
+}
+
+// WithTemplates adds additional templates which may be used in the templates.
+func WithTemplates(paths ...string) Option {
+	return func(o *options) {
+		o.templates = append(o.templates, paths...)
+	}
+}
+
+// WithDelimiters overrides the default template delimiters "{{" and "}}".
+func WithDelimiters(left string, right string) Option {
+	return func(o *options) {
+		o.delimiters = []string{left, right}
+	}
+}
+
+/*
+checkTemplateOptions returns an error if one of the given options only applies to the execution of the tasks,
+as it would be silently ignored by the given function rendering a template.
+*/
+func (o *options) checkTemplateOptions(function string) error {
+	if len(o.runnerOptions) > 0 {
+		return OrbitError.NewOrbitErrorf("option(s) %s do(es) not apply to %s", strings.Join(o.runnerOptions, ", "), function)
+	}
+
+	return nil
+}
+
+// WithStdin sets the Stdin of the commands (default os.Stdin).
+func WithStdin(stdin io.Reader) Option {
+	return func(o *options) {
+		o.stdin = stdin
+		o.runnerOptions = append(o.runnerOptions, "WithStdin")
+	}
+}
+
+// WithStdout sets the Stdout of the commands (default os.Stdout).
+func WithStdout(stdout io.Writer) Option {
+	return func(o *options) {
+		o.stdout = stdout
+		o.runnerOptions = append(o.runnerOptions, "WithStdout")
+	}
+}
+
+// WithStderr sets the Stderr of the commands (default os.Stderr).
+func WithStderr(stderr io.Writer) Option {
+	return func(o *options) {
+		o.stderr = stderr
+		o.runnerOptions = append(o.runnerOptions, "WithStderr")
+	}
+}
+
+// WithEnv adds environment variables (key=value) to the environment of the commands.
+func WithEnv(env ...string) Option {
+	return func(o *options) {
+		o.env = append(o.env, env...)
+		o.runnerOptions = append(o.runnerOptions, "WithEnv")
+	}
+}
+
+// WithDir sets the working directory of the commands (default the current directory).
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+		o.runnerOptions = append(o.runnerOptions, "WithDir")
+	}
+}
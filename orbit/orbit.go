@@ -0,0 +1,252 @@
+/*
+Package orbit is the public API of Orbit: it allows to render data-driven templates
+and to run tasks from Go code, without calling the orbit executable.
+
+	data, err := orbit.Render("template.yml", orbit.WithPayload(map[string]interface{}{"name": "falcon"}))
+
+	r, err := orbit.NewRunner("orbit.yml", orbit.WithStdout(&out), orbit.WithDir("path/to/project"))
+	err = r.Run("build", "test")
+
+Unlike the orbit executable, the payload is only given by the WithPayload option: the file
+orbit-payload.yml is not read.
+
+The logger of Orbit is global: its level, its output and the secrets it masks (e.g. the hidden
+inputs and the secrets of the payloads) are shared by every Runner and every rendering of a process.
+A secret given to one of them is therefore masked in the output of the others too.
+*/
+package orbit
+
+import (
+	"context"
+
+	OrbitContext "github.com/gulien/orbit/app/context"
+	OrbitError "github.com/gulien/orbit/app/error"
+	"github.com/gulien/orbit/app/generator"
+	"github.com/gulien/orbit/app/helpers"
+	"github.com/gulien/orbit/app/runner"
+
+	"gopkg.in/yaml.v2"
+)
+
+// tasksFilePath is displayed instead of a configuration file path for tasks given programmatically.
+const tasksFilePath = "(tasks)"
+
+type (
+	/*
+		Task is a task as defined in a configuration file, except its variables:
+		the tasks given programmatically are not data-driven templates.
+	*/
+	Task struct {
+		// Use is the name of the task.
+		Use string `yaml:"use"`
+
+		// Short is the short description of the task.
+		Short string `yaml:"short,omitempty"`
+
+		// Shell is the binary (and its parameters) which will be called to run the commands.
+		Shell string `yaml:"shell,omitempty"`
+
+		// Private allows to hide the task.
+		Private bool `yaml:"private,omitempty"`
+
+		// Prompt is a question the user has to confirm before running the task.
+		Prompt string `yaml:"prompt,omitempty"`
+
+		// Inputs array contains the values asked to the user before running the task.
+		Inputs []Input `yaml:"inputs,omitempty"`
+
+		// Output is the way the output of the commands is displayed:
+		// interleaved (default), prefixed, grouped or file.
+		Output string `yaml:"output,omitempty"`
+
+		// TTY allows to run the commands inside a pseudo-terminal when Stdout is a terminal.
+		TTY bool `yaml:"tty,omitempty"`
+
+		// Lock is the optional name of a lock preventing concurrent executions of the task.
+		Lock string `yaml:"lock,omitempty"`
+
+		// Sources array contains the patterns of the files read by the commands.
+		Sources []string `yaml:"sources,omitempty"`
+
+		// Generates array contains the patterns of the files generated by the commands.
+		Generates []string `yaml:"generates,omitempty"`
+
+		// Cache allows to restore the generated files from the cache instead of running the commands.
+		Cache bool `yaml:"cache,omitempty"`
+
+		// Env array contains the names of the environment variables whose values are part of the cache key of the task.
+		Env []string `yaml:"env,omitempty"`
+
+		// Run is the stack of commands to execute. A command "run@task1,task2" runs others tasks.
+		Run []string `yaml:"run"`
+	}
+
+	// Input is a value asked to the user before running a task, given to its commands as an environment variable.
+	Input struct {
+		// Name is the name of the environment variable which will contain the value.
+		Name string `yaml:"name"`
+
+		// Prompt is the question displayed to the user.
+		Prompt string `yaml:"prompt,omitempty"`
+
+		// Default is the value used if the user gives an empty answer.
+		Default string `yaml:"default,omitempty"`
+
+		// Choices array restricts the values the user may give.
+		Choices []string `yaml:"choices,omitempty"`
+
+		// Hidden allows to hide the value typed by the user (e.g. passwords).
+		Hidden bool `yaml:"hidden,omitempty"`
+	}
+
+	// Runner runs tasks.
+	Runner struct {
+		// runner is the underlying instance of OrbitRunner.
+		runner *runner.OrbitRunner
+
+		// ctx allows to cancel the execution of the tasks.
+		ctx context.Context
+	}
+)
+
+/*
+Render executes a data-driven template and returns the resulting bytes.
+
+The options which only apply to the execution of the tasks (WithStdin, WithStdout,
+WithStderr, WithEnv and WithDir) are rejected.
+*/
+func Render(templateFilePath string, opts ...Option) ([]byte, error) {
+	o := newOptions(opts)
+	if err := o.checkTemplateOptions("Render"); err != nil {
+		return nil, err
+	}
+
+	orbitContext, err := newOrbitContext(templateFilePath, o)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := generator.NewOrbitGenerator(orbitContext).Execute(o.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
+
+/*
+Generate executes a data-driven template and writes the result into the given output file,
+and the files generated by the output function next to it.
+
+As with Render, the options which only apply to the execution of the tasks are rejected.
+*/
+func Generate(templateFilePath string, outputPath string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.checkTemplateOptions("Generate"); err != nil {
+		return err
+	}
+
+	orbitContext, err := newOrbitContext(templateFilePath, o)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		return OrbitError.NewOrbitError("no output file given")
+	}
+
+	g := generator.NewOrbitGenerator(orbitContext)
+	g.SetOutputFile(outputPath)
+	data, err := g.Execute(o.ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := g.Flush(outputPath, data); err != nil {
+		return err
+	}
+
+	return g.FlushOutputs()
+}
+
+// NewRunner creates an instance of Runner from a configuration file, which may be a data-driven template.
+func NewRunner(configFilePath string, opts ...Option) (*Runner, error) {
+	o := newOptions(opts)
+
+	orbitContext, err := newOrbitContext(configFilePath, o)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := runner.NewOrbitRunner(o.ctx, orbitContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRunner(r, o), nil
+}
+
+// NewRunnerFromTasks creates an instance of Runner from the given tasks.
+func NewRunnerFromTasks(tasks []Task, opts ...Option) (*Runner, error) {
+	o := newOptions(opts)
+
+	data, err := yaml.Marshal(map[string][]Task{"tasks": tasks})
+	if err != nil {
+		return nil, OrbitError.NewOrbitErrorf("unable to encode the tasks. Details:\n%s", err)
+	}
+
+	orbitContext := &OrbitContext.OrbitContext{
+		TemplateFilePath:   tasksFilePath,
+		Payload:            o.payload,
+		Templates:          o.templates,
+		TemplateDelimiters: o.delimiters,
+	}
+
+	r, err := runner.NewOrbitRunnerFromData(orbitContext, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRunner(r, o), nil
+}
+
+// newRunner creates an instance of Runner from an instance of OrbitRunner configured with the given options.
+func newRunner(r *runner.OrbitRunner, o *options) *Runner {
+	r.SetStdio(o.stdin, o.stdout, o.stderr)
+	r.SetEnv(o.env)
+	r.SetDir(o.dir)
+
+	return &Runner{runner: r, ctx: o.ctx}
+}
+
+/*
+Run runs the given tasks one by one.
+
+Once the context given by the WithContext option is done, the running
+command and its child processes are killed and Run returns an error.
+*/
+func (r *Runner) Run(names ...string) error {
+	return r.runner.Run(r.ctx, names...)
+}
+
+// newOrbitContext creates an instance of OrbitContext from the given options.
+func newOrbitContext(templateFilePath string, o *options) (*OrbitContext.OrbitContext, error) {
+	if templateFilePath == "" {
+		return nil, OrbitError.NewOrbitError("no data-driven template given")
+	}
+
+	if !helpers.FileExists(templateFilePath) {
+		return nil, OrbitError.NewOrbitErrorf("the data-driven template %s does not exist", templateFilePath)
+	}
+
+	if len(o.delimiters) != 2 {
+		return nil, OrbitError.NewOrbitErrorf("%d delimiter(s) specified: %+v. Exactly two (left,right) must be specified", len(o.delimiters), o.delimiters)
+	}
+
+	return &OrbitContext.OrbitContext{
+		TemplateFilePath:   templateFilePath,
+		Payload:            o.payload,
+		Templates:          o.templates,
+		TemplateDelimiters: o.delimiters,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package orbit
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests if rendering a data-driven template throws an error if it's broken
+// or no error if it's correct.
+func TestRender(t *testing.T) {
+	// case 1: uses a non existing template.
+	if _, err := Render("non_existing_file"); err == nil {
+		t.Error("Non existing template should not have been rendered!")
+	}
+
+	// case 2: uses a template with a missing payload.
+	templateFilePath, _ := filepath.Abs("../_tests/template-alternative-delimiters.yml")
+	if _, err := Render(templateFilePath, WithDelimiters("<<", ">>")); err == nil {
+		t.Error("Template should not have been rendered without its payload!")
+	}
+
+	// case 3: uses a template with its payload.
+	payload := map[string]interface{}{
+		"SPACEX_LAUNCHERS":      "Falcon 9",
+		"BLUE_ORIGIN_LAUNCHERS": "New Glenn",
+		"ESA_LAUNCHERS":         "Vega",
+	}
+
+	data, err := Render(templateFilePath, WithDelimiters("<<", ">>"), WithPayload(payload))
+	if err != nil || !strings.Contains(string(data), "Falcon 9") {
+		t.Error("Template should have been rendered with its payload!")
+	}
+
+	// case 4: uses a cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Render(templateFilePath, WithDelimiters("<<", ">>"), WithPayload(payload), WithContext(ctx)); err == nil {
+		t.Error("Template should not have been rendered with a cancelled context!")
+	}
+
+	// case 5: uses an option which only applies to the execution of the tasks.
+	if _, err := Render(templateFilePath, WithDelimiters("<<", ">>"), WithPayload(payload), WithDir(".")); err == nil {
+		t.Error("Template should not have been rendered with an option of the runner!")
+	}
+}
+
+// Tests if generating a file from a data-driven template works as expected.
+func TestGenerate(t *testing.T) {
+	templateFilePath, _ := filepath.Abs("../_tests/template-with-additional-templates.txt")
+	spacexTemplateFilePath, _ := filepath.Abs("../_tests/template-spacex.txt")
+	blueOriginTemplateFilePath, _ := filepath.Abs("../_tests/template-blue-origin.txt")
+
+	// case 1: uses no output file.
+	if err := Generate(templateFilePath, ""); err == nil {
+		t.Error("Template should not have been generated without an output file!")
+	}
+
+	// case 2: uses a correct output file.
+	if err := Generate(templateFilePath, "result.txt", WithTemplates(spacexTemplateFilePath, blueOriginTemplateFilePath)); err != nil {
+		t.Error("Template should have been generated!")
+	}
+
+	os.Remove("result.txt")
+}
+
+// Tests if running tasks from a configuration file or from tasks given programmatically works as expected.
+func TestRunner(t *testing.T) {
+	// case 1: uses a configuration file.
+	var out bytes.Buffer
+	configFilePath, _ := filepath.Abs("../_tests/orbit.yml")
+	r, err := NewRunner(configFilePath, WithStdout(&out))
+	if err != nil {
+		t.Fatal("Runner should have been instantiated!")
+	}
+
+	if err := r.Run("explorer"); err != nil || out.String() != "I am explorer task\n" {
+		t.Errorf("Task should have been run, got %q!", out.String())
+	}
+
+	// case 2: uses tasks given programmatically with some environment variables and a working directory.
+	dir, _ := ioutil.TempDir("", "orbit")
+	defer os.RemoveAll(dir)
+
+	out.Reset()
+	tasks := []Task{
+		{Use: "falcon", Run: []string{"echo $LAUNCHER > launcher.txt", "run@heavy"}},
+		{Use: "heavy", Output: "prefixed", Run: []string{"cat launcher.txt"}},
+	}
+
+	r, err = NewRunnerFromTasks(tasks, WithStdout(&out), WithEnv("LAUNCHER=Falcon Heavy"), WithDir(dir))
+	if err != nil {
+		t.Fatal("Runner should have been instantiated!")
+	}
+
+	if err := r.Run("falcon"); err != nil || out.String() != "[heavy] Falcon Heavy\n" {
+		t.Errorf("Tasks should have been run, got %q!", out.String())
+	}
+
+	// case 3: uses a task with an input given by an environment variable.
+	out.Reset()
+	os.Setenv("ORBIT_LAUNCHER", "Falcon 9")
+	defer os.Unsetenv("ORBIT_LAUNCHER")
+	tasks = append(tasks, Task{
+		Use:    "falcon 9",
+		Inputs: []Input{{Name: "ORBIT_LAUNCHER", Choices: []string{"Falcon 9", "Falcon Heavy"}}},
+		Run:    []string{"echo $ORBIT_LAUNCHER"},
+	})
+
+	r, _ = NewRunnerFromTasks(tasks, WithStdout(&out), WithDir(dir))
+	if err := r.Run("falcon 9"); err != nil || out.String() != "Falcon 9\n" {
+		t.Errorf("Task should have been run with its input, got %q!", out.String())
+	}
+
+	// case 4: uses a cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, _ = NewRunnerFromTasks(tasks, WithContext(ctx), WithDir(dir))
+	if err := r.Run("heavy"); err == nil {
+		t.Error("Task should not have been run with a cancelled context!")
+	}
+}